@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/intrange"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// buildResult holds the outcome of building a single pkgbase
+type buildResult struct {
+	base       dep.Base
+	pkgdests   map[string]string
+	pkgVersion string
+	// upToDate is set when --needed found the base already installed
+	upToDate bool
+	output   *bytes.Buffer
+	err      error
+}
+
+// buildOutput returns where a build prints to, the terminal when out is nil
+func buildOutput(out io.Writer) io.Writer {
+	if out == nil {
+		return os.Stdout
+	}
+
+	return out
+}
+
+// runBuildCmd runs a makepkg command for a pkgbase. When out is nil the
+// command is attached to the terminal, otherwise all output goes to out so
+// parallel builds don't interleave. Unless build logs are disabled the
+// output is also appended to the build log of the pkgbase.
+func runBuildCmd(cmd *exec.Cmd, pkgbase string, out io.Writer) error {
+	if config.BuildLogs == 0 {
+		return runUnlogged(cmd, out)
+	}
+
+	log, err := openBuildLog(pkgbase, cmd)
+	if err != nil {
+		text.Warnln(gotext.Get("failed to open build log for %s: %s", cyan(pkgbase), err))
+		return runUnlogged(cmd, out)
+	}
+
+	if out != nil {
+		defer log.Close()
+		cmd.Stdout = io.MultiWriter(out, log)
+		cmd.Stderr = cmd.Stdout
+		return cmd.Run()
+	}
+
+	// a pipe would hide the terminal from makepkg, script keeps it on one
+	// so it keeps its colors and prompts
+	if _, errScript := exec.LookPath("script"); errScript == nil {
+		if err = log.Close(); err != nil {
+			return err
+		}
+
+		cmd = scriptCmd(cmd, log.Name())
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		return cmd.Run()
+	}
+
+	defer log.Close()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, log)
+	cmd.Stderr = io.MultiWriter(os.Stderr, log)
+	return cmd.Run()
+}
+
+func runUnlogged(cmd *exec.Cmd, out io.Writer) error {
+	if out == nil {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	} else {
+		cmd.Stdout, cmd.Stderr = out, out
+	}
+
+	return cmd.Run()
+}
+
+// scriptCmd wraps cmd in script(1), which runs it on a pseudo terminal and
+// appends everything it prints to logPath
+func scriptCmd(cmd *exec.Cmd, logPath string) *exec.Cmd {
+	quoted := make([]string, 0, len(cmd.Args))
+	for _, arg := range cmd.Args {
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+
+	wrapped := exec.Command("script", "--quiet", "--append", "--return", "--flush",
+		"--command", strings.Join(quoted, " "), logPath)
+	wrapped.Dir = cmd.Dir
+	wrapped.Env = cmd.Env
+	return wrapped
+}
+
+// makepkgSourceArgs returns the makepkg arguments used to download and
+// verify the sources of a pkgbase
+func makepkgSourceArgs(ignoreArch bool) []string {
+	args := []string{"--verifysource", "-Ccf"}
+	if ignoreArch {
+		args = append(args, "--ignorearch")
+	}
+
+	return args
+}
+
+// sourcesCmd returns the command that downloads and verifies the sources of
+// the PKGBUILD in dir
+func sourcesCmd(dir string, ignoreArch bool) *exec.Cmd {
+	return passToMakepkg(dir, makepkgSourceArgs(ignoreArch)...)
+}
+
+// pkgverCmd returns the command that extracts the sources of the PKGBUILD in
+// dir and bumps its pkgver
+func pkgverCmd(dir string, ignoreArch bool) *exec.Cmd {
+	args := []string{"--nobuild", "-fC"}
+	if ignoreArch {
+		args = append(args, "--ignorearch")
+	}
+
+	return passToMakepkg(dir, args...)
+}
+
+// makepkgBuildArgs returns the makepkg arguments used to build a pkgbase
+func makepkgBuildArgs(ignoreArch bool) []string {
+	args := []string{"-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}
+	if ignoreArch {
+		args = append(args, "--ignorearch")
+	}
+
+	return args
+}
+
+// buildCmd returns the command that builds the PKGBUILD in dir, either on
+// the host or in the clean chroot with the packages in inject installed.
+func buildCmd(dir string, inject []string, ignoreArch bool) *exec.Cmd {
+	if config.Chroot {
+		return passToMakechrootpkg(dir, inject, makechrootpkgArgs(ignoreArch)...)
+	}
+
+	return passToMakepkg(dir, makepkgBuildArgs(ignoreArch)...)
+}
+
+// buildPkgbuild bumps the pkgver of a base and builds it unless its
+// packages are already in PKGDEST.
+func buildPkgbuild(cmdArgs *settings.Arguments, dp *dep.Pool, base dep.Base,
+	incompatible stringset.StringSet, inject []string, out io.Writer) (pkgdests map[string]string, pkgVersion string, upToDate bool, err error) {
+	pkg := base.Pkgbase()
+	dir := filepath.Join(config.BuildDir, pkg)
+	built := true
+
+	// pkgver bump
+	if err = runBuildCmd(pkgverCmd(dir, incompatible.Get(pkg)), pkg, out); err != nil {
+		return nil, "", false, errors.New(gotext.Get("error making: %s", base.String()))
+	}
+
+	pkgdests, pkgVersion, err = parsePackageList(dir)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	isExplicit := false
+	for _, b := range base {
+		isExplicit = isExplicit || dp.Explicit.Get(b.Name)
+	}
+	if config.ReBuild == "no" || (config.ReBuild == "yes" && !isExplicit) {
+		for _, split := range base {
+			pkgdest, ok := pkgdests[split.Name]
+			if !ok {
+				return nil, "", false, errors.New(gotext.Get("could not find PKGDEST for: %s", split.Name))
+			}
+
+			if _, errStat := os.Stat(pkgdest); os.IsNotExist(errStat) {
+				built = false
+			} else if errStat != nil {
+				return nil, "", false, errStat
+			}
+		}
+	} else {
+		built = false
+	}
+
+	if cmdArgs.ExistsArg("needed") {
+		installed := true
+		for _, split := range base {
+			if alpmpkg := dp.LocalDB.Pkg(split.Name); alpmpkg == nil || alpmpkg.Version() != pkgVersion {
+				installed = false
+			}
+		}
+
+		if installed {
+			err = runBuildCmd(passToMakepkg(dir, "-c", "--nobuild", "--noextract", "--ignorearch"), pkg, out)
+			if err != nil {
+				return nil, "", false, errors.New(gotext.Get("error making: %s", err))
+			}
+
+			fmt.Fprintln(buildOutput(out), gotext.Get("%s is up to date -- skipping", cyan(pkg+"-"+pkgVersion)))
+			return pkgdests, pkgVersion, true, nil
+		}
+	}
+
+	if built {
+		err = runBuildCmd(passToMakepkg(dir, "-c", "--nobuild", "--noextract", "--ignorearch"), pkg, out)
+		if err != nil {
+			return nil, "", false, errors.New(gotext.Get("error making: %s", err))
+		}
+
+		fmt.Fprintln(buildOutput(out), text.SprintWarn(gotext.Get("%s already made -- skipping build", cyan(pkg+"-"+pkgVersion))))
+	} else {
+		if err = runHooks(hookPreBuild, base, pkgVersion, nil, out); err != nil {
+			return nil, "", false, err
+		}
+
+		if errMake := runBuildCmd(buildCmd(dir, inject, incompatible.Get(pkg)), pkg, out); errMake != nil {
+			return nil, "", false, errors.New(gotext.Get("error making: %s", base.String()))
+		}
+
+		runPostHooks(hookPostBuild, base, pkgVersion, packageFiles(base, pkgdests), out)
+	}
+
+	return pkgdests, pkgVersion, false, nil
+}
+
+// packageFiles returns the package files of a base that exist in PKGDEST
+func packageFiles(base dep.Base, pkgdests map[string]string) []string {
+	files := make([]string, 0, len(base))
+	for _, split := range base {
+		if pkgdest, ok := pkgdests[split.Name]; ok {
+			if _, err := os.Stat(pkgdest); err == nil {
+				files = append(files, pkgdest)
+			}
+		}
+	}
+
+	return files
+}
+
+// buildScheduler hands out bases whose AUR dependencies are installed.
+// Bases are handed out in the order they were resolved in.
+type buildScheduler struct {
+	pending   []dep.Base
+	deps      stringset.MapStringSet
+	installed stringset.StringSet
+}
+
+func newBuildScheduler(do *dep.Order) *buildScheduler {
+	pending := make([]dep.Base, len(do.Aur))
+	copy(pending, do.Aur)
+
+	return &buildScheduler{
+		pending:   pending,
+		deps:      do.BaseDeps,
+		installed: make(stringset.StringSet),
+	}
+}
+
+func (s *buildScheduler) ready(base dep.Base) bool {
+	for depBase := range s.deps[base.Pkgbase()] {
+		if !s.installed.Get(depBase) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// next removes and returns the first pending base that can be built now.
+func (s *buildScheduler) next() (dep.Base, bool) {
+	for i, base := range s.pending {
+		if s.ready(base) {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return base, true
+		}
+	}
+
+	return nil, false
+}
+
+// force removes and returns the first pending base regardless of its
+// dependencies. Used to break dependency cycles.
+func (s *buildScheduler) force() dep.Base {
+	base := s.pending[0]
+	s.pending = s.pending[1:]
+	return base
+}
+
+func (s *buildScheduler) done() bool {
+	return len(s.pending) == 0
+}
+
+// allDeps returns every base pkgbase directly or indirectly depends on
+func (s *buildScheduler) allDeps(pkgbase string) stringset.StringSet {
+	deps := make(stringset.StringSet)
+	stack := []string{pkgbase}
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for depBase := range s.deps[cur] {
+			if !deps.Get(depBase) {
+				deps.Set(depBase)
+				stack = append(stack, depBase)
+			}
+		}
+	}
+
+	return deps
+}
+
+// skipDependants removes and returns every pending base that directly or
+// indirectly depends on pkgbase.
+func (s *buildScheduler) skipDependants(pkgbase string) []dep.Base {
+	skipped := make([]dep.Base, 0)
+	broken := stringset.Make(pkgbase)
+
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(s.pending); i++ {
+			base := s.pending[i]
+			for depBase := range s.deps[base.Pkgbase()] {
+				if broken.Get(depBase) {
+					broken.Set(base.Pkgbase())
+					skipped = append(skipped, base)
+					s.pending = append(s.pending[:i], s.pending[i+1:]...)
+					i--
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return skipped
+}
+
+// buildSummary collects the outcome of every base for --keepgoing
+type buildSummary struct {
+	rows   []summaryRow
+	failed int
+}
+
+type summaryRow struct {
+	pkgbase string
+	status  string
+	color   func(string) string
+	reason  string
+}
+
+func (bs *buildSummary) succeeded(pkgbases ...string) {
+	for _, pkgbase := range pkgbases {
+		bs.rows = append(bs.rows, summaryRow{pkgbase, gotext.Get("succeeded"), green, ""})
+	}
+}
+
+func (bs *buildSummary) fail(pkgbase string, err error) {
+	bs.failed++
+	bs.rows = append(bs.rows, summaryRow{pkgbase, gotext.Get("failed"), red, err.Error()})
+}
+
+func (bs *buildSummary) skip(pkgbase, failed string) {
+	bs.rows = append(bs.rows, summaryRow{pkgbase, gotext.Get("skipped"), magenta, gotext.Get("depends on %s", failed)})
+}
+
+func (bs *buildSummary) print() {
+	if len(bs.rows) == 0 {
+		return
+	}
+
+	nameWidth, statusWidth := 0, 0
+	for _, row := range bs.rows {
+		nameWidth = intrange.Max(nameWidth, len(row.pkgbase))
+		statusWidth = intrange.Max(statusWidth, len(row.status))
+	}
+
+	text.OperationInfoln(gotext.Get("Build summary:"))
+	for _, row := range bs.rows {
+		status := row.color(fmt.Sprintf("%-*s", statusWidth, row.status))
+		fmt.Printf("    %-*s  %s  %s\n", nameWidth, row.pkgbase, status, row.reason)
+	}
+}
+
+func (bs *buildSummary) err() error {
+	if bs.failed == 0 {
+		return nil
+	}
+
+	return errors.New(gotext.Get("%d of %d AUR packages failed to build", bs.failed, len(bs.rows)))
+}
+
+// printBuildOutput prints the buffered output of a parallel build in one piece
+func printBuildOutput(res *buildResult) {
+	if res.output == nil || res.output.Len() == 0 {
+		return
+	}
+
+	text.OperationInfoln(gotext.Get("Build output: %s", cyan(res.base.String())))
+	_, _ = os.Stdout.Write(res.output.Bytes())
+}
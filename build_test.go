@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+)
+
+func makeBase(name string) dep.Base {
+	return dep.Base{&rpc.Pkg{Name: name, PackageBase: name}}
+}
+
+func TestBuildScheduler(t *testing.T) {
+	do := &dep.Order{
+		Aur:      []dep.Base{makeBase("a"), makeBase("b"), makeBase("c"), makeBase("d")},
+		BaseDeps: make(stringset.MapStringSet),
+	}
+	do.BaseDeps.Add("c", "a")
+	do.BaseDeps.Add("d", "c")
+
+	s := newBuildScheduler(do)
+
+	base, ok := s.next()
+	assert.True(t, ok)
+	assert.Equal(t, "a", base.Pkgbase())
+
+	base, ok = s.next()
+	assert.True(t, ok)
+	assert.Equal(t, "b", base.Pkgbase())
+
+	_, ok = s.next()
+	assert.False(t, ok, "c must wait for a to be installed")
+
+	s.installed.Set("a")
+	base, ok = s.next()
+	assert.True(t, ok)
+	assert.Equal(t, "c", base.Pkgbase())
+
+	_, ok = s.next()
+	assert.False(t, ok)
+	assert.False(t, s.done())
+	assert.Equal(t, "d", s.force().Pkgbase())
+	assert.True(t, s.done())
+}
+
+func TestBuildSchedulerSkipDependants(t *testing.T) {
+	do := &dep.Order{
+		Aur:      []dep.Base{makeBase("a"), makeBase("b"), makeBase("c"), makeBase("d")},
+		BaseDeps: make(stringset.MapStringSet),
+	}
+	do.BaseDeps.Add("d", "c")
+	do.BaseDeps.Add("c", "a")
+
+	s := newBuildScheduler(do)
+
+	base, ok := s.next()
+	assert.True(t, ok)
+	assert.Equal(t, "a", base.Pkgbase())
+
+	skipped := make([]string, 0)
+	for _, base := range s.skipDependants("a") {
+		skipped = append(skipped, base.Pkgbase())
+	}
+	assert.ElementsMatch(t, []string{"c", "d"}, skipped)
+
+	base, ok = s.next()
+	assert.True(t, ok)
+	assert.Equal(t, "b", base.Pkgbase())
+	assert.True(t, s.done())
+}
+
+func TestBuildSchedulerAllDeps(t *testing.T) {
+	do := &dep.Order{
+		Aur:      []dep.Base{makeBase("a"), makeBase("b"), makeBase("c"), makeBase("d")},
+		BaseDeps: make(stringset.MapStringSet),
+	}
+	do.BaseDeps.Add("d", "c")
+	do.BaseDeps.Add("c", "a")
+	do.BaseDeps.Add("c", "b")
+
+	s := newBuildScheduler(do)
+
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, s.allDeps("d").ToSlice())
+	assert.Empty(t, s.allDeps("a"))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	alpm "github.com/Jguer/go-alpm"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// buildLogStamp names the log files of this run, so every makepkg call for a
+// pkgbase during one transaction ends up in the same file
+var buildLogStamp = time.Now().Format("2006-01-02_15-04-05")
+
+// buildLogDir holds the logs of a pkgbase
+func buildLogDir(pkgbase string) string {
+	return filepath.Join(config.Runtime.LogsPath, pkgbase)
+}
+
+// buildLogs returns the log files of a pkgbase, oldest first
+func buildLogs(pkgbase string) ([]string, error) {
+	files, err := ioutil.ReadDir(buildLogDir(pkgbase))
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".log") {
+			logs = append(logs, filepath.Join(buildLogDir(pkgbase), file.Name()))
+		}
+	}
+
+	sort.Strings(logs)
+	return logs, nil
+}
+
+// rotateBuildLogs removes the oldest logs of a pkgbase until only
+// config.BuildLogs are left
+func rotateBuildLogs(pkgbase string) {
+	logs, err := buildLogs(pkgbase)
+	if err != nil {
+		return
+	}
+
+	for len(logs) > config.BuildLogs {
+		if err = os.Remove(logs[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		logs = logs[1:]
+	}
+}
+
+// openBuildLog opens the log file of a pkgbase for this run and writes a
+// header for cmd to it
+func openBuildLog(pkgbase string, cmd *exec.Cmd) (*os.File, error) {
+	dir := buildLogDir(pkgbase)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, buildLogStamp+".log")
+	_, errStat := os.Stat(path)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if os.IsNotExist(errStat) {
+		rotateBuildLogs(pkgbase)
+	}
+
+	fmt.Fprintf(file, "==> %s: %s\n", time.Now().Format(time.RFC3339), strings.Join(cmd.Args, " "))
+	return file, nil
+}
+
+// printBuildLogs prints the latest build log of every target. Split
+// packages are looked up by their pkgbase.
+func printBuildLogs(targets []string, alpmHandle *alpm.Handle) error {
+	if len(targets) == 0 {
+		return errors.New(gotext.Get("no packages given"))
+	}
+
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	missing := false
+	for _, target := range targets {
+		pkgbase := target
+		if _, errStat := os.Stat(buildLogDir(pkgbase)); os.IsNotExist(errStat) {
+			if pkg := localDB.Pkg(target); pkg != nil && pkg.Base() != "" {
+				pkgbase = pkg.Base()
+			}
+		}
+
+		logs, err := buildLogs(pkgbase)
+		if err != nil || len(logs) == 0 {
+			text.Errorln(gotext.Get("no build log found for %s", cyan(target)))
+			missing = true
+			continue
+		}
+
+		latest := logs[len(logs)-1]
+		text.OperationInfoln(gotext.Get("Build log: %s", cyan(latest)))
+
+		file, err := os.Open(latest)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(os.Stdout, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if missing {
+		return fmt.Errorf("")
+	}
+
+	return nil
+}
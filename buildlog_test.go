@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/settings"
+)
+
+func TestRotateBuildLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-buildlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldConfig := config
+	defer func() { config = oldConfig }()
+	config = settings.MakeConfig()
+	config.Runtime = &settings.Runtime{LogsPath: dir}
+	config.BuildLogs = 2
+
+	logDir := buildLogDir("yay")
+	assert.NoError(t, os.MkdirAll(logDir, 0o755))
+	for _, name := range []string{"2020-01-01_00-00-00.log", "2020-01-02_00-00-00.log"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(logDir, name), nil, 0o644))
+	}
+
+	log, err := openBuildLog("yay", exec.Command("makepkg", "-cf"))
+	assert.NoError(t, err)
+	assert.NoError(t, log.Close())
+
+	// reopening the log of this run must not rotate again
+	log, err = openBuildLog("yay", exec.Command("makepkg", "--verifysource"))
+	assert.NoError(t, err)
+	assert.NoError(t, log.Close())
+
+	logs, err := buildLogs("yay")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(logDir, "2020-01-02_00-00-00.log"),
+		filepath.Join(logDir, buildLogStamp+".log"),
+	}, logs)
+
+	content, err := ioutil.ReadFile(logs[1])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "makepkg -cf")
+	assert.Contains(t, string(content), "makepkg --verifysource")
+}
+
+func TestRunBuildCmdLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-buildlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldConfig := config
+	defer func() { config = oldConfig }()
+	config = settings.MakeConfig()
+	config.Runtime = &settings.Runtime{LogsPath: dir}
+
+	config.BuildLogs = 0
+	assert.NoError(t, runBuildCmd(exec.Command("true"), "nolog", nil))
+	_, err = os.Stat(buildLogDir("nolog"))
+	assert.True(t, os.IsNotExist(err))
+
+	config.BuildLogs = 2
+	assert.NoError(t, runBuildCmd(exec.Command("printf", `%s-%s\n`, "it's", "built"), "yay", nil))
+	assert.Error(t, runBuildCmd(exec.Command("false"), "yay", nil))
+
+	logs, err := buildLogs("yay")
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(logs[len(logs)-1])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "printf %s-%s\\n it's built")
+	assert.Contains(t, string(content), "it's-built")
+}
+
+func TestScriptCmd(t *testing.T) {
+	cmd := exec.Command("makepkg", "--config", "/etc/it's.conf")
+	cmd.Dir = "/build/yay"
+
+	wrapped := scriptCmd(cmd, "/logs/yay.log")
+	assert.Equal(t, []string{"script", "--quiet", "--append", "--return", "--flush",
+		"--command", `'makepkg' '--config' '/etc/it'\''s.conf'`, "/logs/yay.log"}, wrapped.Args)
+	assert.Equal(t, "/build/yay", wrapped.Dir)
+}
@@ -9,6 +9,7 @@ import (
 	alpm "github.com/Jguer/go-alpm"
 	"github.com/leonelquinteros/gotext"
 
+	"github.com/Jguer/yay/v10/pkg/dep"
 	"github.com/Jguer/yay/v10/pkg/settings"
 	"github.com/Jguer/yay/v10/pkg/text"
 )
@@ -23,41 +24,48 @@ func questionCallback(question alpm.QuestionAny) {
 		return
 	}
 
-	if settings.HideMenus {
-		return
-	}
-
-	size := 0
+	names := make([]string, 0)
+	dbs := make([]string, 0)
 
 	_ = qp.Providers(config.Runtime.AlpmHandle).ForEach(func(pkg alpm.Package) error {
-		size++
+		names = append(names, pkg.Name())
+		dbs = append(dbs, pkg.DB().Name())
 		return nil
 	})
 
-	str := text.Bold(gotext.Get("There are %d providers available for %s:\n", size, qp.Dep()))
+	// provider rules answer without asking
+	kept, chosen := dep.ChooseProvider(config.ProviderRules, qp.Dep().String(), names)
+	if chosen >= 0 {
+		qp.SetUseIndex(chosen)
+		return
+	}
 
-	size = 1
-	var db string
+	if settings.HideMenus || len(kept) == 0 {
+		return
+	}
 
-	_ = qp.Providers(config.Runtime.AlpmHandle).ForEach(func(pkg alpm.Package) error {
-		thisDB := pkg.DB().Name()
+	// the default is the first provider the rules don't avoid
+	qp.SetUseIndex(kept[0])
+	size := len(kept)
+
+	str := text.Bold(gotext.Get("There are %d providers available for %s:\n", size, qp.Dep()))
 
-		if db != thisDB {
-			db = thisDB
+	var db string
+	for n, i := range kept {
+		if db != dbs[i] {
+			db = dbs[i]
 			str += text.SprintOperationInfo(gotext.Get("Repository"), db, "\n    ")
 		}
-		str += fmt.Sprintf("%d) %s ", size, pkg.Name())
-		size++
-		return nil
-	})
+		str += fmt.Sprintf("%d) %s ", n+1, names[i])
+	}
 
 	text.OperationInfoln(str)
 
 	for {
-		fmt.Print(gotext.Get("\nEnter a number (default=1): "))
+		fmt.Fprint(text.Out(), gotext.Get("\nEnter a number (default=1): "))
 
 		if config.NoConfirm {
-			fmt.Println()
+			fmt.Fprintln(text.Out())
 			break
 		}
 
@@ -88,7 +96,7 @@ func questionCallback(question alpm.QuestionAny) {
 			continue
 		}
 
-		qp.SetUseIndex(num - 1)
+		qp.SetUseIndex(kept[num-1])
 		break
 	}
 }
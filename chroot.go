@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// chrootRoot is the clean chroot makechrootpkg copies for every build
+func chrootRoot() string {
+	return filepath.Join(config.ChrootDir, "root")
+}
+
+func passToSudo(bin string, args ...string) *exec.Cmd {
+	argArr := []string{config.SudoBin}
+	argArr = append(argArr, strings.Fields(config.SudoFlags)...)
+	argArr = append(argArr, bin)
+	argArr = append(argArr, args...)
+
+	return exec.Command(argArr[0], argArr[1:]...)
+}
+
+// ensureChroot creates the clean chroot if it does not exist yet, otherwise
+// it brings it up to date.
+func ensureChroot() error {
+	root := chrootRoot()
+
+	if _, err := os.Stat(root); err == nil {
+		text.OperationInfoln(gotext.Get("Updating chroot: %s", cyan(root)))
+		if err = show(passToSudo("arch-nspawn", root, "pacman", "-Syu", "--noconfirm")); err != nil {
+			return errors.New(gotext.Get("error updating chroot: %s", err))
+		}
+
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(config.ChrootDir, 0o755); err != nil {
+		return errors.New(gotext.Get("failed to create chroot directory '%s': %s", config.ChrootDir, err))
+	}
+
+	args := []string{"-C", config.PacmanConf}
+	if config.MakepkgConf != "" {
+		args = append(args, "-M", config.MakepkgConf)
+	}
+	args = append(args, root, "base-devel")
+
+	text.OperationInfoln(gotext.Get("Creating chroot: %s", cyan(root)))
+	if err := show(passToSudo("mkarchroot", args...)); err != nil {
+		return errors.New(gotext.Get("error creating chroot: %s", err))
+	}
+
+	return nil
+}
+
+// passToMakechrootpkg builds the PKGBUILD in dir inside a copy of the clean
+// chroot. Every pkgbase gets a copy of its own, named after it, so parallel
+// builds don't share one. The packages in inject are installed into the copy
+// first, so AUR dependencies built earlier in the same transaction are
+// available.
+func passToMakechrootpkg(dir string, inject []string, args ...string) *exec.Cmd {
+	chrootArgs := []string{"-c", "-r", config.ChrootDir, "-l", filepath.Base(dir)}
+	for _, pkg := range inject {
+		chrootArgs = append(chrootArgs, "-I", pkg)
+	}
+
+	chrootArgs = append(chrootArgs, "--")
+	chrootArgs = append(chrootArgs, args...)
+	chrootArgs = append(chrootArgs, strings.Fields(config.MFlags)...)
+
+	cmd := exec.Command("makechrootpkg", chrootArgs...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// makechrootpkgArgs returns the makepkg arguments used to build a pkgbase in
+// the chroot. Sources are extracted again inside the chroot.
+func makechrootpkgArgs(ignoreArch bool) []string {
+	args := []string{"-f", "--noconfirm", "--holdver"}
+	if ignoreArch {
+		args = append(args, "--ignorearch")
+	}
+
+	return args
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
-	"net/http"
 	"os"
 	"time"
 
 	alpm "github.com/Jguer/go-alpm"
 	"github.com/leonelquinteros/gotext"
 
+	"github.com/Jguer/yay/v10/pkg/aurhttp"
 	"github.com/Jguer/yay/v10/pkg/completion"
 	"github.com/Jguer/yay/v10/pkg/intrange"
 	"github.com/Jguer/yay/v10/pkg/news"
@@ -47,14 +48,22 @@ If no operation is provided -Y will be assumed
 New options:
        --repo             Assume targets are from the repositories
     -a --aur              Assume targets are from the AUR
+       --json             Print search results, package info, upgrade lists
+                          and dependency graphs as JSON
+       --format <format>  Print them as json or text
 
 Permanent configuration options:
     --save                Causes the following options to be saved back to the
                           config file when used
 
     --aururl      <url>   Set an alternative AUR URL
+    --aurmetadata <file>  Read AUR metadata from a dump such as
+                          packages-meta-ext-v1.json.gz instead of the RPC
     --builddir    <dir>   Directory used to download and run PKGBUILDS
     --absdir      <dir>   Directory used to store downloads from the ABS
+    --chrootdir   <dir>   Directory used to store the clean chroot
+    --localreponame <name> Name of the local repository for built packages
+    --localrepodir  <dir>  Directory of the local repository for built packages
     --editor      <file>  Editor to use when editing PKGBUILDs
     --editorflags <flags> Pass arguments to editor
     --makepkg     <file>  makepkg command to use
@@ -69,9 +78,21 @@ Permanent configuration options:
     --nomakepkgconf       Use the default makepkg.conf
 
     --requestsplitn <n>   Max amount of packages to query per AUR request
+    --buildjobs     <n>   Max amount of AUR packages to build in parallel
+    --downloadjobs  <n>   Max amount of AUR sources to download in parallel
+    --buildlogs     <n>   Amount of build logs to keep per AUR package in
+                          $XDG_STATE_HOME/yay/logs, 0 disables them
+    --scanthreshold <s>   Severity of PKGBUILD findings that forces a review
+                          and aborts --noconfirm: low, medium, high or none
     --completioninterval  <n> Time in days to refresh completion cache
+    --aurcachetime  <n>   Time in minutes -Si and -Ps show cached AUR package
+                          info for. Other commands always ask the AUR and
+                          only use the cache when it can't be reached
+    --refresh-aur         Refresh the cached AUR package info
     --sortby    <field>   Sort AUR results by a specific field during search
-    --searchby  <field>   Search for packages using a specified field
+    --searchby  <field>   Search for packages using a specified field. With
+                          --searchindex, also keywords, description and
+                          provides
     --answerclean   <a>   Set a predetermined answer for the clean build menu
     --answerdiff    <a>   Set a predetermined answer for the diff menu
     --answeredit    <a>   Set a predetermined answer for the edit pkgbuild menu
@@ -116,6 +137,17 @@ Permanent configuration options:
     --nocombinedupgrade   Perform the repo upgrade and AUR upgrade separately
     --batchinstall        Build multiple AUR packages then install them together
     --nobatchinstall      Build and install each AUR package one by one
+    --searchindex         Search the AUR offline in a local copy of its
+                          metadata, refreshed like the completion cache
+    --nosearchindex       Search the AUR through the RPC
+    --keepgoing           Skip failed AUR packages and their dependants and
+                          keep building the rest
+    --nokeepgoing         Stop at the first AUR package that fails to build
+    --chroot              Build AUR packages in a clean chroot using devtools
+    --nochroot            Build AUR packages on the host
+    --localrepo           Add built AUR packages to a local repository and
+                          install them from it with pacman -S
+    --nolocalrepo         Install built AUR packages with pacman -U
     --fuzzy               Use fuzzy finding instead of number selection
 	--nofuzzy             Use number selection instead of fuzzy finding
 	--langcheck           Print languages used in AUR repository
@@ -131,6 +163,10 @@ Permanent configuration options:
     --timeupdate          Check packages' AUR page for changes during sysupgrade
     --notimeupdate        Do not check packages' AUR page for changes
 
+sync specific options:
+    --print-plan          Print the resolved transaction as JSON without
+                          refreshing, building or installing anything
+
 show specific options:
     -c --complete         Used for completions
     -d --defaultconfig    Print default yay configuration
@@ -138,16 +174,41 @@ show specific options:
     -s --stats            Display system package statistics
     -w --news             Print arch news
     -p --pkgbuild         Print pkgbuild of packages
+       --log              Print the latest build log of packages
+       --history          Print the transactions yay ran, or the ones that
+                          touched the given packages
+       --why              Print why installed packages are installed
+       --graph            Print the dependency graph of packages in the dot
+                          format, or as json with --json
+       --rebuild-check    Print the AUR packages that link to missing
+                          libraries or were built for an older python, and
+                          offer to rebuild them
 
 yay specific options:
     -c --clean            Remove unneeded dependencies
        --gendb            Generates development package DB used for updating
+       --resume           Continue the last AUR transaction that failed
+       --sync-file <file> Install the packages listed in a manifest and mark
+                          them as explicitly installed
+       --export           With --sync-file, write the explicitly installed
+                          packages to the manifest instead
+       --prune            With --sync-file, remove explicitly installed
+                          packages that are not in the manifest
+       --pin              Pin AUR packages given as <pkg>[@commit] to a commit
+                          of their AUR repository, or list the pins
+       --unpin            Remove the pins of AUR packages
+       --trust            Accept PKGBUILD changes of AUR packages without a
+                          review while they keep their maintainer, or list
+                          the trusted packages and maintainers
+       --trustmaintainer  Accept PKGBUILD changes of every AUR package of the
+                          given maintainers without a review
+       --untrust          Remove the trust of packages or maintainers
 
 getpkgbuild specific options:
     -f --force            Force download for existing ABS packages`)
 }
 
-func handleCmd(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
+func handleCmd(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) (err error) {
 	if cmdArgs.ExistsArg("h", "help") {
 		return handleHelp(cmdArgs)
 	}
@@ -156,6 +217,14 @@ func handleCmd(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
 		sudoLoopBackground()
 	}
 
+	if recordsHistory(cmdArgs) {
+		currentHistory = beginHistory(alpmHandle)
+		defer func() {
+			currentHistory.finish(err)
+			currentHistory = nil
+		}()
+	}
+
 	switch cmdArgs.Op {
 	case "V", "version":
 		handleVersion()
@@ -249,6 +318,16 @@ func handlePrint(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) (err erro
 		err = localStatistics(alpmHandle)
 	case cmdArgs.ExistsArg("p", "pkgbuild"):
 		err = printPkgbuilds(cmdArgs.Targets, alpmHandle)
+	case cmdArgs.ExistsArg("log"):
+		err = printBuildLogs(cmdArgs.Targets, alpmHandle)
+	case cmdArgs.ExistsArg("history"):
+		err = printHistory(cmdArgs.Targets)
+	case cmdArgs.ExistsArg("why"):
+		err = printWhy(cmdArgs.Targets, alpmHandle)
+	case cmdArgs.ExistsArg("graph"):
+		err = printDependencyGraph(os.Stdout, cmdArgs, alpmHandle)
+	case cmdArgs.ExistsArg("rebuild-check"):
+		err = rebuildCheck(cmdArgs, alpmHandle)
 	default:
 		err = nil
 	}
@@ -259,6 +338,34 @@ func handleYay(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
 	if cmdArgs.ExistsArg("gendb") {
 		return createDevelDB(config.Runtime.VCSPath, alpmHandle)
 	}
+	if cmdArgs.ExistsArg("resume") {
+		return resumeInstall(alpmHandle)
+	}
+	if cmdArgs.ExistsArg("pin") {
+		if len(cmdArgs.Targets) == 0 {
+			return printPins()
+		}
+		return pinPackages(cmdArgs.Targets, alpmHandle)
+	}
+	if cmdArgs.ExistsArg("unpin") {
+		return unpinPackages(cmdArgs.Targets, alpmHandle)
+	}
+	if cmdArgs.ExistsArg("trust", "trustmaintainer") {
+		if len(cmdArgs.Targets) == 0 {
+			printTrust()
+			return nil
+		}
+		return trustTargets(cmdArgs.Targets, cmdArgs.ExistsArg("trustmaintainer"))
+	}
+	if cmdArgs.ExistsArg("untrust") {
+		return untrustTargets(cmdArgs.Targets)
+	}
+	if path, _, ok := cmdArgs.GetArg("sync-file"); ok {
+		if cmdArgs.ExistsArg("export") {
+			return exportManifest(path, alpmHandle)
+		}
+		return syncManifest(cmdArgs, path, alpmHandle, cmdArgs.ExistsArg("prune"))
+	}
 	if cmdArgs.ExistsDouble("c") {
 		return cleanDependencies(cmdArgs, alpmHandle, true)
 	}
@@ -296,7 +403,7 @@ func handleSync(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
 		} else {
 			config.SearchMode = detailed
 		}
-		return syncSearch(targets, alpmHandle)
+		return syncSearch(os.Stdout, targets, alpmHandle)
 	}
 	if cmdArgs.ExistsArg("p", "print", "print-format") {
 		return show(passToPacman(cmdArgs))
@@ -311,7 +418,11 @@ func handleSync(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
 		return show(passToPacman(cmdArgs))
 	}
 	if cmdArgs.ExistsArg("i", "info") {
-		return syncInfo(cmdArgs, targets, alpmHandle)
+		return syncInfo(os.Stdout, cmdArgs, targets, alpmHandle)
+	}
+	if cmdArgs.ExistsArg("print-plan") {
+		cmdArgs.DelArg("print-plan")
+		return printPlan(os.Stdout, cmdArgs, alpmHandle)
 	}
 	if cmdArgs.ExistsArg("u", "sysupgrade") {
 		return install(cmdArgs, alpmHandle, false)
@@ -574,13 +685,12 @@ func syncList(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
 			return err
 		}
 
-		resp, err := http.Get(config.AURURL + "/packages.gz")
+		body, err := aurhttp.Default.Get(config.AURURL + "/packages.gz")
 		if err != nil {
 			return err
 		}
-		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := bufio.NewScanner(bytes.NewReader(body))
 
 		scanner.Scan()
 		for scanner.Scan() {
@@ -105,6 +105,12 @@ var cacheHome string
 // savedInfo holds the current vcs info
 var savedInfo vcsInfo
 
+// savedPins holds the pinned AUR packages
+var savedPins = make(pinStore)
+
+// savedTrust holds the trusted AUR maintainers
+var savedTrust = &trustStore{Pkgbases: make(map[string]string)}
+
 // YayConf holds the current config values for yay.
 var config *settings.Configuration
 
@@ -264,7 +270,7 @@ func editor() (editor string, args []string) {
 func getInput(defaultValue string) (string, error) {
 	text.Info()
 	if defaultValue != "" || config.NoConfirm {
-		fmt.Println(defaultValue)
+		fmt.Fprintln(text.Out(), defaultValue)
 		return defaultValue, nil
 	}
 
@@ -30,6 +30,25 @@ func gitUpdateSeenRef(path, name string) error {
 	return nil
 }
 
+// gitSeenMaintainerKey is the git config key holding the AUR maintainer at
+// the time of the last reviewed diff
+const gitSeenMaintainerKey = "yay.seenmaintainer"
+
+func gitUpdateSeenMaintainer(path, name, maintainer string) error {
+	_, stderr, err := capture(passToGit(filepath.Join(path, name), "config", "--local", gitSeenMaintainerKey, maintainer))
+	if err != nil {
+		return fmt.Errorf("%s %s", stderr, err)
+	}
+	return nil
+}
+
+// Returns the AUR maintainer at the time of the last reviewed diff, if it is
+// known
+func getSeenMaintainer(path, name string) (string, bool) {
+	stdout, _, err := capture(passToGit(filepath.Join(path, name), "config", "--local", "--get", gitSeenMaintainerKey))
+	return stdout, err == nil
+}
+
 // Return wether or not we have reviewed a diff yet. It checks for the existence of
 // YAY_DIFF_REVIEW in the git ref-list
 func gitHasLastSeenRef(path, name string) bool {
@@ -53,10 +72,10 @@ func getLastSeenHash(path, name string) (string, error) {
 }
 
 // Check whether or not a diff exists between the last reviewed diff and
-// HEAD@{upstream}
+// HEAD@{upstream}, or the pinned commit
 func gitHasDiff(path, name string) (bool, error) {
 	if gitHasLastSeenRef(path, name) {
-		stdout, stderr, err := capture(passToGit(filepath.Join(path, name), "rev-parse", gitDiffRefName, "HEAD@{upstream}"))
+		stdout, stderr, err := capture(passToGit(filepath.Join(path, name), "rev-parse", gitDiffRefName, gitMergeTarget(name)))
 		if err != nil {
 			return false, fmt.Errorf("%s%s", stderr, err)
 		}
@@ -132,6 +151,16 @@ func gitDownload(url, path, name string) (bool, error) {
 }
 
 func gitMerge(path, name string) error {
+	if commit, ok := savedPins[name]; ok {
+		text.Warnln(gotext.Get("%s is pinned to %s", cyan(name), shortCommit(commit)))
+		_, stderr, err := capture(passToGit(filepath.Join(path, name), "reset", "--hard", commit))
+		if err != nil {
+			return fmt.Errorf(gotext.Get("error resetting %s: %s", name, stderr))
+		}
+
+		return nil
+	}
+
 	_, stderr, err := capture(passToGit(filepath.Join(path, name), "reset", "--hard", "HEAD"))
 	if err != nil {
 		return fmt.Errorf(gotext.Get("error resetting %s: %s", name, stderr))
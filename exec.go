@@ -82,6 +82,16 @@ func waitLock(dbPath string) {
 }
 
 func passToPacman(args *settings.Arguments) *exec.Cmd {
+	argArr := pacmanArgs(args)
+
+	if args.NeedRoot(config.Runtime) {
+		waitLock(config.Runtime.PacmanConf.DBPath)
+	}
+	return exec.Command(argArr[0], argArr[1:]...)
+}
+
+// pacmanArgs builds the full pacman command line, including sudo when needed
+func pacmanArgs(args *settings.Arguments) []string {
 	argArr := make([]string, 0)
 
 	mSudoFlags := strings.Fields(config.SudoFlags)
@@ -101,10 +111,7 @@ func passToPacman(args *settings.Arguments) *exec.Cmd {
 	argArr = append(argArr, "--config", config.PacmanConf, "--")
 	argArr = append(argArr, args.Targets...)
 
-	if args.NeedRoot(config.Runtime) {
-		waitLock(config.Runtime.PacmanConf.DBPath)
-	}
-	return exec.Command(argArr[0], argArr[1:]...)
+	return argArr
 }
 
 func passToMakepkg(dir string, args ...string) *exec.Cmd {
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	alpm "github.com/Jguer/go-alpm"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/settings"
+)
+
+// writeDot writes a dependency graph in the graphviz format. AUR packages are
+// blue, installed packages dashed and build dependencies filled.
+func writeDot(w io.Writer, g *dep.Graph) error {
+	var b strings.Builder
+
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		attrs := []string{"label=" + strconv.Quote(strings.TrimSpace(node.Name+"\n"+node.Version))}
+		styles := make([]string, 0, 2)
+
+		switch {
+		case node.Virtual:
+			attrs = append(attrs, "shape=ellipse")
+			styles = append(styles, "dotted")
+		case node.Source == "aur":
+			attrs = append(attrs, "color=blue")
+		}
+
+		if node.Target {
+			attrs = append(attrs, "penwidth=2")
+		}
+		if node.Installed {
+			styles = append(styles, "dashed")
+		}
+		if node.MakeOnly || node.CheckOnly {
+			styles = append(styles, "filled")
+			if node.MakeOnly {
+				attrs = append(attrs, "fillcolor=lightgrey")
+			} else {
+				attrs = append(attrs, "fillcolor=lightyellow")
+			}
+		}
+		if len(styles) > 0 {
+			attrs = append(attrs, "style="+strconv.Quote(strings.Join(styles, ",")))
+		}
+
+		fmt.Fprintf(&b, "\t%s [%s];\n", strconv.Quote(node.Name), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range g.Edges {
+		attrs := "label=" + edge.Type
+		switch edge.Type {
+		case dep.EdgeMakeDepends, dep.EdgeCheckDepends:
+			attrs += ", style=dashed"
+		case dep.EdgeProvides:
+			attrs += ", style=dotted, arrowhead=empty"
+		}
+
+		fmt.Fprintf(&b, "\t%s -> %s [%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), attrs)
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// printDependencyGraph resolves the targets like an install would and prints
+// their dependency graph as dot or, with --json, as json
+func printDependencyGraph(w io.Writer, cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
+	warnings := query.NewWarnings()
+	dp, err := dep.GetPool(cmdArgs.Targets,
+		warnings, alpmHandle, config.Runtime.Mode,
+		false, config.NoConfirm, config.Provides, config.ReBuild, config.RequestSplitN, config.ProviderRules)
+	if err != nil {
+		return err
+	}
+
+	g := dp.Graph()
+
+	if config.Runtime.JSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(g)
+	}
+
+	return writeDot(w, g)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+)
+
+func TestWriteDot(t *testing.T) {
+	g := &dep.Graph{
+		Nodes: []*dep.GraphNode{
+			{Name: "yay", Version: "10.0.4-1", Source: "aur", Target: true},
+			{Name: "go", Version: "2:1.15.2-1", Source: "community", MakeOnly: true},
+			{Name: "sh", Virtual: true, MakeOnly: true},
+			{Name: "bash", Version: "5.0.018-1", Source: "local", Installed: true, MakeOnly: true},
+		},
+		Edges: []dep.GraphEdge{
+			{From: "yay", To: "go", Type: dep.EdgeMakeDepends},
+			{From: "go", To: "sh", Type: dep.EdgeDepends},
+			{From: "bash", To: "sh", Type: dep.EdgeProvides},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeDot(&buf, g))
+	assert.Equal(t, `digraph dependencies {
+	rankdir=LR;
+	node [shape=box];
+	"yay" [label="yay\n10.0.4-1", color=blue, penwidth=2];
+	"go" [label="go\n2:1.15.2-1", fillcolor=lightgrey, style="filled"];
+	"sh" [label="sh", shape=ellipse, fillcolor=lightgrey, style="dotted,filled"];
+	"bash" [label="bash\n5.0.018-1", fillcolor=lightgrey, style="dashed,filled"];
+	"yay" -> "go" [label=makedepends, style=dashed];
+	"go" -> "sh" [label=depends];
+	"bash" -> "sh" [label=provides, style=dotted, arrowhead=empty];
+}
+`, buf.String())
+}
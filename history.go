@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	alpm "github.com/Jguer/go-alpm"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// Actions of a package in a transaction
+const (
+	historyInstall   = "install"
+	historyUpgrade   = "upgrade"
+	historyDowngrade = "downgrade"
+	historyReinstall = "reinstall"
+	historyRemove    = "remove"
+)
+
+// historyTransaction is a transaction yay drove. The history file holds one
+// per line.
+type historyTransaction struct {
+	Time     time.Time        `json:"time"`
+	Command  []string         `json:"command"`
+	Error    string           `json:"error,omitempty"`
+	MakeDeps []string         `json:"makedeps,omitempty"`
+	Packages []historyPackage `json:"packages"`
+
+	before  map[string]pkgSnapshot
+	commits map[string]string
+}
+
+type historyPackage struct {
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+	OldVersion string `json:"oldversion,omitempty"`
+	NewVersion string `json:"newversion,omitempty"`
+	Pkgbase    string `json:"pkgbase,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+}
+
+type pkgSnapshot struct {
+	Version     string
+	Base        string
+	InstallDate time.Time
+}
+
+// currentHistory is the transaction being recorded, nil when the operation
+// doesn't change the system
+var currentHistory *historyTransaction
+
+// recordsHistory returns whether an operation may change the installed
+// packages
+func recordsHistory(cmdArgs *settings.Arguments) bool {
+	switch cmdArgs.Op {
+	case "R", "remove", "S", "sync", "U", "upgrade":
+		return cmdArgs.NeedRoot(config.Runtime)
+	case "Y", "yay":
+		if cmdArgs.ExistsArg("pin", "unpin", "trust", "trustmaintainer", "untrust", "gendb", "export") {
+			return false
+		}
+		return len(cmdArgs.Targets) > 0 || cmdArgs.ExistsArg("resume", "sync-file", "c", "clean")
+	}
+
+	return false
+}
+
+func snapshotLocalDB(alpmHandle *alpm.Handle) (map[string]pkgSnapshot, error) {
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]pkgSnapshot)
+	_ = localDB.PkgCache().ForEach(func(pkg alpm.Package) error {
+		snapshot[pkg.Name()] = pkgSnapshot{pkg.Version(), pkg.Base(), pkg.InstallDate()}
+		return nil
+	})
+
+	return snapshot, nil
+}
+
+func beginHistory(alpmHandle *alpm.Handle) *historyTransaction {
+	before, err := snapshotLocalDB(alpmHandle)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+
+	return &historyTransaction{
+		Time:    time.Now(),
+		Command: os.Args,
+		before:  before,
+		commits: make(map[string]string),
+	}
+}
+
+// noteMake records the packages installed as make dependencies
+func (h *historyTransaction) noteMake(pkgs []string) {
+	if h == nil {
+		return
+	}
+
+	h.MakeDeps = append(h.MakeDeps, pkgs...)
+}
+
+// noteBuilt records the AUR commit a pkgbase was built from
+func (h *historyTransaction) noteBuilt(pkgbase string) {
+	if h == nil {
+		return
+	}
+
+	stdout, _, err := capture(passToGit(filepath.Join(config.BuildDir, pkgbase), "rev-parse", "HEAD"))
+	if err == nil {
+		h.commits[pkgbase] = stdout
+	}
+}
+
+// diffSnapshots returns the packages that changed between two snapshots of
+// the local database
+func diffSnapshots(before, after map[string]pkgSnapshot) []historyPackage {
+	pkgs := make([]historyPackage, 0)
+
+	for name, newPkg := range after {
+		hp := historyPackage{Name: name, NewVersion: newPkg.Version}
+
+		oldPkg, ok := before[name]
+		switch {
+		case !ok:
+			hp.Action = historyInstall
+		case oldPkg.Version != newPkg.Version:
+			hp.Action = historyUpgrade
+			if alpm.VerCmp(oldPkg.Version, newPkg.Version) > 0 {
+				hp.Action = historyDowngrade
+			}
+		case !oldPkg.InstallDate.Equal(newPkg.InstallDate):
+			hp.Action = historyReinstall
+		default:
+			continue
+		}
+
+		if ok {
+			hp.OldVersion = oldPkg.Version
+		}
+
+		pkgs = append(pkgs, hp)
+	}
+
+	for name, oldPkg := range before {
+		if _, ok := after[name]; !ok {
+			pkgs = append(pkgs, historyPackage{Name: name, Action: historyRemove, OldVersion: oldPkg.Version})
+		}
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	return pkgs
+}
+
+// finish compares the local database to the one the transaction started
+// with and appends the transaction to the history
+func (h *historyTransaction) finish(errOp error) {
+	if h == nil {
+		return
+	}
+
+	alpmHandle, err := initAlpmHandle(config.Runtime.PacmanConf, config.Runtime.AlpmHandle)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	config.Runtime.AlpmHandle = alpmHandle
+
+	after, err := snapshotLocalDB(alpmHandle)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	h.Packages = diffSnapshots(h.before, after)
+	for i := range h.Packages {
+		pkg := &h.Packages[i]
+		if newPkg, ok := after[pkg.Name]; ok {
+			pkg.Pkgbase = newPkg.Base
+		} else {
+			pkg.Pkgbase = h.before[pkg.Name].Base
+		}
+		pkg.Commit = h.commits[pkg.Pkgbase]
+	}
+
+	if errOp != nil {
+		h.Error = errOp.Error()
+		if h.Error == "" {
+			h.Error = gotext.Get("failed")
+		}
+	}
+
+	if len(h.Packages) == 0 && errOp == nil {
+		return
+	}
+
+	if err = h.save(config.Runtime.HistoryPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (h *historyTransaction) save(path string) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func loadHistory(path string) ([]*historyTransaction, error) {
+	history := make([]*historyTransaction, 0)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		h := &historyTransaction{}
+		if err = json.Unmarshal(scanner.Bytes(), h); err != nil {
+			return nil, fmt.Errorf(gotext.Get("failed to read history file '%s': %s", path, err))
+		}
+		history = append(history, h)
+	}
+
+	return history, scanner.Err()
+}
+
+// printHistory prints the recorded transactions, only the ones that touched
+// targets if there are any
+func printHistory(targets []string) error {
+	history, err := loadHistory(config.Runtime.HistoryPath)
+	if err != nil {
+		return err
+	}
+
+	filter := stringset.FromSlice(targets)
+	for _, h := range history {
+		makeDeps := stringset.FromSlice(h.MakeDeps)
+		pkgs := make([]historyPackage, 0, len(h.Packages))
+		for _, pkg := range h.Packages {
+			if len(filter) == 0 || filter.Get(pkg.Name) || filter.Get(pkg.Pkgbase) {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+
+		if len(filter) > 0 && len(pkgs) == 0 {
+			continue
+		}
+
+		status := green(gotext.Get("ok"))
+		if h.Error != "" {
+			status = red(gotext.Get("failed: %s", h.Error))
+		}
+
+		fmt.Printf("%s %s %s %s\n", bold(blue("::")), bold(h.Time.Format("2006-01-02 15:04:05")),
+			strings.Join(h.Command, " "), status)
+
+		for _, pkg := range pkgs {
+			line := fmt.Sprintf("    %-9s %s", pkg.Action, cyan(pkg.Name))
+			switch {
+			case pkg.OldVersion != "" && pkg.NewVersion != "" && pkg.OldVersion != pkg.NewVersion:
+				line += " " + pkg.OldVersion + " -> " + pkg.NewVersion
+			case pkg.NewVersion != "":
+				line += " " + pkg.NewVersion
+			default:
+				line += " " + pkg.OldVersion
+			}
+
+			if pkg.Commit != "" {
+				line += " " + gotext.Get("(aur %s)", shortCommit(pkg.Commit))
+			}
+			if makeDeps.Get(pkg.Name) {
+				line += " " + magenta(gotext.Get("make dependency"))
+			}
+
+			fmt.Println(line)
+		}
+
+		if len(filter) == 0 && len(h.MakeDeps) > 0 {
+			fmt.Printf("    %s %s\n", gotext.Get("make dependencies:"), strings.Join(h.MakeDeps, " "))
+		}
+	}
+
+	if len(history) == 0 {
+		text.Warnln(gotext.Get("no transactions recorded yet"))
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	then := time.Unix(1600000000, 0)
+	now := time.Unix(1600001000, 0)
+
+	before := map[string]pkgSnapshot{
+		"bash": {"5.0.018-1", "bash", then},
+		"nano": {"5.2-1", "nano", then},
+		"go":   {"2:1.15.2-1", "go", then},
+	}
+	after := map[string]pkgSnapshot{
+		"bash": {"5.0.018-1", "bash", then},
+		"go":   {"2:1.15.2-1", "go", now},
+		"yay":  {"10.0.4-1", "yay", now},
+	}
+
+	assert.Equal(t, []historyPackage{
+		{Name: "go", Action: historyReinstall, OldVersion: "2:1.15.2-1", NewVersion: "2:1.15.2-1"},
+		{Name: "nano", Action: historyRemove, OldVersion: "5.2-1"},
+		{Name: "yay", Action: historyInstall, NewVersion: "10.0.4-1"},
+	}, diffSnapshots(before, after))
+}
+
+func TestHistoryStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-history")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history.jsonl")
+	history, err := loadHistory(path)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	first := &historyTransaction{
+		Command:  []string{"yay", "-S", "yay"},
+		MakeDeps: []string{"go"},
+		Packages: []historyPackage{{Name: "yay", Action: historyInstall, NewVersion: "10.0.4-1", Pkgbase: "yay", Commit: "0123456789"}},
+	}
+	second := &historyTransaction{
+		Command:  []string{"yay", "-R", "yay"},
+		Error:    "exit status 1",
+		Packages: []historyPackage{},
+	}
+	assert.NoError(t, first.save(path))
+	assert.NoError(t, second.save(path))
+
+	history, err = loadHistory(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []*historyTransaction{first, second}, history)
+}
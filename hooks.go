@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// Stages user hooks can run at. Hooks are executables named after the stage,
+// either directly in the hooks directory for every pkgbase or in a
+// subdirectory named after a pkgbase.
+const (
+	hookPostDownload = "post-download"
+	hookPreBuild     = "pre-build"
+	hookPostBuild    = "post-build"
+	hookPostInstall  = "post-install"
+)
+
+// hookPaths returns the global and the per pkgbase hook of a stage that exist
+func hookPaths(stage, pkgbase string) []string {
+	paths := make([]string, 0, 2)
+
+	for _, path := range []string{
+		filepath.Join(config.Runtime.HooksPath, stage),
+		filepath.Join(config.Runtime.HooksPath, pkgbase, stage),
+	} {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+func hookEnv(stage string, base dep.Base, pkgVersion string, pkgdests []string) []string {
+	names := make([]string, 0, len(base))
+	for _, split := range base {
+		names = append(names, split.Name)
+	}
+
+	if pkgVersion == "" {
+		pkgVersion = base.Version()
+	}
+
+	return append(os.Environ(),
+		"YAY_HOOK="+stage,
+		"YAY_PKGBASE="+base.Pkgbase(),
+		"YAY_PKGNAMES="+strings.Join(names, " "),
+		"YAY_VERSION="+pkgVersion,
+		"YAY_BUILDDIR="+filepath.Join(config.BuildDir, base.Pkgbase()),
+		"YAY_PKGDEST="+strings.Join(pkgdests, " "),
+	)
+}
+
+// hookError is a failed hook. It aborts the base the hook ran for, not the
+// transaction.
+type hookError struct {
+	msg string
+}
+
+func (e *hookError) Error() string {
+	return e.msg
+}
+
+// runHooks runs the hooks of a stage for a pkgbase in its build directory.
+// pkgdests holds the package files once they are built.
+func runHooks(stage string, base dep.Base, pkgVersion string, pkgdests []string, out io.Writer) error {
+	for _, path := range hookPaths(stage, base.Pkgbase()) {
+		cmd := exec.Command(path)
+		cmd.Dir = filepath.Join(config.BuildDir, base.Pkgbase())
+		cmd.Env = hookEnv(stage, base, pkgVersion, pkgdests)
+
+		if err := runBuildCmd(cmd, base.Pkgbase(), out); err != nil {
+			return &hookError{gotext.Get("%s hook %s failed for %s", stage, path, base.String())}
+		}
+	}
+
+	return nil
+}
+
+// runPostHooks runs hooks whose failure doesn't affect the transaction
+func runPostHooks(stage string, base dep.Base, pkgVersion string, pkgdests []string, out io.Writer) {
+	if err := runHooks(stage, base, pkgVersion, pkgdests, out); err != nil {
+		text.Warnln(err)
+	}
+}
+
+// runPostDownloadHooks runs the post-download hooks of every base of do. They
+// may patch the PKGBUILDs, so the .SRCINFO of a base is regenerated once its
+// hooks ran. A base whose hooks fail is dropped from do along with the bases
+// depending on it, the way --keepgoing drops failed builds, and the returned
+// error names the failed bases.
+func runPostDownloadHooks(do *dep.Order) error {
+	failed := make([]string, 0)
+
+	for _, base := range do.Aur {
+		if len(hookPaths(hookPostDownload, base.Pkgbase())) == 0 {
+			continue
+		}
+
+		err := runHooks(hookPostDownload, base, "", nil, nil)
+		if err == nil {
+			err = writeSrcinfo(base)
+		}
+
+		if err != nil {
+			text.Errorln(err)
+			failed = append(failed, base.Pkgbase())
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	dropped := stringset.FromSlice(failed)
+	scheduler := newBuildScheduler(do)
+	for _, pkgbase := range failed {
+		for _, base := range scheduler.skipDependants(pkgbase) {
+			text.Warnln(gotext.Get("skipping %s: depends on %s", cyan(base.String()), cyan(pkgbase)))
+			dropped.Set(base.Pkgbase())
+		}
+	}
+
+	kept := make([]dep.Base, 0, len(do.Aur))
+	for _, base := range do.Aur {
+		if !dropped.Get(base.Pkgbase()) {
+			kept = append(kept, base)
+		}
+	}
+	do.Aur = kept
+
+	return errors.New(gotext.Get("%s hooks failed for %s", hookPostDownload, strings.Join(failed, ", ")))
+}
+
+// writeSrcinfo regenerates the .SRCINFO of a base from its PKGBUILD
+func writeSrcinfo(base dep.Base) error {
+	dir := filepath.Join(config.BuildDir, base.Pkgbase())
+
+	stdout, stderr, err := capture(passToMakepkg(dir, "--printsrcinfo"))
+	if err != nil {
+		return errors.New(gotext.Get("error generating .SRCINFO for %s: %s", base.String(), stderr))
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, ".SRCINFO"), []byte(stdout+"\n"), 0644)
+}
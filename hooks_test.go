@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+)
+
+// fakeMakepkg answers the makepkg calls yay makes around a build and touches
+// "built" when it is asked to build
+const fakeMakepkg = `#!/bin/sh
+case "$*" in
+*--packagelist*) echo "$PWD/foo-2.0-1-any.pkg.tar.zst" ;;
+*--printsrcinfo*) printf 'pkgbase = foo\n\tpkgver = 2.0\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n' ;;
+*--nobuild*) ;;
+*) touch built ;;
+esac
+`
+
+func writeScript(t *testing.T, path, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0o755))
+}
+
+func setupHookTest(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "yay-hooks")
+	assert.NoError(t, err)
+
+	oldConfig := config
+	config = settings.MakeConfig()
+	config.Runtime = &settings.Runtime{HooksPath: filepath.Join(dir, "hooks")}
+	config.BuildDir = filepath.Join(dir, "build")
+	config.BuildLogs = 0
+	config.MakepkgBin = filepath.Join(dir, "makepkg")
+
+	writeScript(t, config.MakepkgBin, fakeMakepkg)
+	assert.NoError(t, os.MkdirAll(filepath.Join(config.BuildDir, "foo"), 0o755))
+
+	return func() {
+		config = oldConfig
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHookPaths(t *testing.T) {
+	cleanup := setupHookTest(t)
+	defer cleanup()
+
+	hooks := config.Runtime.HooksPath
+	writeScript(t, filepath.Join(hooks, hookPreBuild), "#!/bin/sh\n")
+	writeScript(t, filepath.Join(hooks, "foo", hookPreBuild), "#!/bin/sh\n")
+	assert.NoError(t, os.MkdirAll(filepath.Join(hooks, "bar", hookPreBuild), 0o755))
+
+	assert.Equal(t, []string{
+		filepath.Join(hooks, hookPreBuild),
+		filepath.Join(hooks, "foo", hookPreBuild),
+	}, hookPaths(hookPreBuild, "foo"))
+	assert.Equal(t, []string{filepath.Join(hooks, hookPreBuild)}, hookPaths(hookPreBuild, "bar"))
+	assert.Empty(t, hookPaths(hookPostInstall, "foo"))
+}
+
+func TestHookEnv(t *testing.T) {
+	cleanup := setupHookTest(t)
+	defer cleanup()
+
+	base := dep.Base{
+		{Name: "foo", PackageBase: "foo", Version: "1.0-1"},
+		{Name: "foo-docs", PackageBase: "foo", Version: "1.0-1"},
+	}
+
+	env := hookEnv(hookPostBuild, base, "", []string{"/pkg/foo.pkg.tar.zst", "/pkg/foo-docs.pkg.tar.zst"})
+	assert.Subset(t, env, []string{
+		"YAY_HOOK=post-build",
+		"YAY_PKGBASE=foo",
+		"YAY_PKGNAMES=foo foo-docs",
+		"YAY_VERSION=1.0-1",
+		"YAY_BUILDDIR=" + filepath.Join(config.BuildDir, "foo"),
+		"YAY_PKGDEST=/pkg/foo.pkg.tar.zst /pkg/foo-docs.pkg.tar.zst",
+	})
+
+	env = hookEnv(hookPreBuild, base, "2.0-1", nil)
+	assert.Contains(t, env, "YAY_VERSION=2.0-1")
+	assert.Contains(t, env, "YAY_PKGDEST=")
+}
+
+func TestFailingPreBuildHookAbortsBuild(t *testing.T) {
+	cleanup := setupHookTest(t)
+	defer cleanup()
+
+	writeScript(t, filepath.Join(config.Runtime.HooksPath, "foo", hookPreBuild), "#!/bin/sh\nexit 1\n")
+
+	base := dep.Base{{Name: "foo", PackageBase: "foo", Version: "2.0-1"}}
+	dp := &dep.Pool{Explicit: make(stringset.StringSet)}
+
+	_, _, _, err := buildPkgbuild(settings.MakeArguments(), dp, base, make(stringset.StringSet), nil, nil)
+	assert.EqualError(t, err, gotext.Get("%s hook %s failed for %s", hookPreBuild,
+		filepath.Join(config.Runtime.HooksPath, "foo", hookPreBuild), base.String()))
+	assert.IsType(t, &hookError{}, err, "only the base is aborted")
+
+	_, err = os.Stat(filepath.Join(config.BuildDir, "foo", "built"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPostDownloadHookRegeneratesSrcinfo(t *testing.T) {
+	cleanup := setupHookTest(t)
+	defer cleanup()
+
+	srcinfo := filepath.Join(config.BuildDir, "foo", ".SRCINFO")
+	assert.NoError(t, ioutil.WriteFile(srcinfo,
+		[]byte("pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n"), 0o644))
+
+	base := dep.Base{{Name: "foo", PackageBase: "foo", Version: "1.0-1"}}
+	assert.NoError(t, runPostDownloadHooks(&dep.Order{Aur: []dep.Base{base}}))
+	parsed, err := gosrc.ParseFile(srcinfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0-1", parsed.Version(), "bases without hooks keep their .SRCINFO")
+
+	writeScript(t, filepath.Join(config.Runtime.HooksPath, hookPostDownload), "#!/bin/sh\n")
+	assert.NoError(t, runPostDownloadHooks(&dep.Order{Aur: []dep.Base{base}}))
+	parsed, err = gosrc.ParseFile(srcinfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0-1", parsed.Version())
+}
+
+func TestFailingPostDownloadHookDropsBase(t *testing.T) {
+	cleanup := setupHookTest(t)
+	defer cleanup()
+
+	writeScript(t, filepath.Join(config.Runtime.HooksPath, "foo", hookPostDownload), "#!/bin/sh\nexit 1\n")
+
+	foo := dep.Base{{Name: "foo", PackageBase: "foo", Version: "1.0-1"}}
+	app := dep.Base{{Name: "app", PackageBase: "app", Version: "1.0-1"}}
+	bar := dep.Base{{Name: "bar", PackageBase: "bar", Version: "1.0-1"}}
+	do := &dep.Order{
+		Aur:      []dep.Base{foo, app, bar},
+		BaseDeps: stringset.MapStringSet{"app": stringset.Make("foo")},
+	}
+
+	assert.EqualError(t, runPostDownloadHooks(do), gotext.Get("%s hooks failed for %s", hookPostDownload, "foo"))
+	assert.Equal(t, []dep.Base{bar}, do.Aur)
+}
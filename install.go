@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -61,7 +63,19 @@ func asexp(cmdArgs *settings.Arguments, pkgs []string) error {
 }
 
 // Install handles package installs
-func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProviders bool) (err error) {
+func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProviders bool) error {
+	return runInstall(cmdArgs, alpmHandle, ignoreProviders, nil)
+}
+
+// printPlan resolves the transaction an install would run and writes it to w
+// as json, without refreshing, building or installing anything
+func printPlan(w io.Writer, cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
+	return runInstall(cmdArgs, alpmHandle, false, w)
+}
+
+// runInstall installs the targets, or only writes the plan to planOut when
+// it is set
+func runInstall(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProviders bool, planOut io.Writer) (err error) {
 	var incompatible stringset.StringSet
 	var do *dep.Order
 
@@ -72,7 +86,9 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 
 	warnings := query.NewWarnings()
 
-	if config.Runtime.Mode == settings.ModeAny || config.Runtime.Mode == settings.ModeRepo {
+	planOnly := planOut != nil
+
+	if !planOnly && (config.Runtime.Mode == settings.ModeAny || config.Runtime.Mode == settings.ModeRepo) {
 		if config.CombinedUpgrade {
 			if cmdArgs.ExistsArg("y", "refresh") {
 				err = earlyRefresh(cmdArgs)
@@ -105,6 +121,7 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 	localNamesCache := stringset.FromSlice(localNames)
 
 	requestTargets := cmdArgs.Copy().Targets
+	ignore := make(stringset.StringSet)
 
 	// create the arguments to pass for the repo install
 	arguments := cmdArgs.Copy()
@@ -126,9 +143,10 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 
 		warnings.Print()
 
-		ignore, aurUp, errUp := upgradePkgs(aurUp, repoUp)
-		if errUp != nil {
-			return errUp
+		var aurNames stringset.StringSet
+		ignore, aurNames, err = upgradePkgs(aurUp, repoUp)
+		if err != nil {
+			return err
 		}
 
 		for _, up := range repoUp {
@@ -138,7 +156,7 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 			}
 		}
 
-		for up := range aurUp {
+		for up := range aurNames {
 			requestTargets = append(requestTargets, "aur/"+up)
 			cmdArgs.AddTarget("aur/" + up)
 		}
@@ -152,7 +170,7 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 
 	dp, err := dep.GetPool(requestTargets,
 		warnings, alpmHandle, config.Runtime.Mode,
-		ignoreProviders, config.NoConfirm, config.Provides, config.ReBuild, config.RequestSplitN)
+		ignoreProviders, config.NoConfirm, config.Provides, config.ReBuild, config.RequestSplitN, config.ProviderRules)
 	if err != nil {
 		return err
 	}
@@ -164,7 +182,7 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 		}
 	}
 
-	if len(dp.Aur) == 0 {
+	if len(dp.Aur) == 0 && !planOnly {
 		if !config.CombinedUpgrade {
 			if cmdArgs.ExistsArg("u", "sysupgrade") {
 				fmt.Println(gotext.Get(" there is nothing to do"))
@@ -180,13 +198,14 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 		return show(passToPacman(cmdArgs))
 	}
 
-	if len(dp.Aur) > 0 && os.Geteuid() == 0 {
+	if len(dp.Aur) > 0 && os.Geteuid() == 0 && !planOnly {
 		return fmt.Errorf(gotext.Get("refusing to install AUR packages as root, aborting"))
 	}
 
 	var conflicts stringset.MapStringSet
 	if !cmdArgs.ExistsDouble("d", "nodeps") {
-		conflicts, err = dp.CheckConflicts(config.UseAsk, config.NoConfirm)
+		// conflicts are part of the plan and not an error
+		conflicts, err = dp.CheckConflicts(config.UseAsk || planOnly, config.NoConfirm)
 		if err != nil {
 			return err
 		}
@@ -205,12 +224,22 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 		arguments.AddTarget(pkg)
 	}
 
+	if planOnly {
+		plan := makeTransactionPlan(cmdArgs, arguments, do, conflicts, ignore, planIncompatible(do.Aur, alpmHandle))
+		return printTransactionPlan(planOut, plan)
+	}
+
 	if len(do.Aur) == 0 && len(arguments.Targets) == 0 && (!cmdArgs.ExistsArg("u", "sysupgrade") || config.Runtime.Mode == settings.ModeAUR) {
 		fmt.Println(gotext.Get(" there is nothing to do"))
 		return nil
 	}
 
 	do.Print()
+	if localDB, err := alpmHandle.LocalDB(); err == nil {
+		do.Sizes(localDB, config.Runtime.PacmanConf.CacheDir).Print()
+	}
+	printWhyMake(do)
+	currentHistory.noteMake(do.GetMake())
 
 	if config.CleanAfter {
 		defer cleanAfter(do.Aur)
@@ -255,18 +284,41 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 	var toDiff []dep.Base
 	var toEdit []dep.Base
 
-	if config.DiffMenu {
-		pkgbuildNumberMenu(do.Aur, remoteNamesCache)
-		toDiff, err = diffNumberMenu(do.Aur, remoteNamesCache)
+	toReview, err := scanPkgbuilds(do.Aur)
+	if err != nil {
+		return err
+	}
+
+	trusted, changed := reviewMaintainers(do.Aur)
+	toReview = append(toReview, changed...)
+	trusted = removeBases(trusted, toReview)
+
+	if len(trusted) > 0 {
+		text.OperationInfoln(gotext.Get("Accepting the changes of trusted maintainers"))
+		if err = updatePkgbuildSeenRef(trusted); err != nil {
+			text.Errorln(err.Error())
+		}
+	}
+
+	if untrusted := removeBases(do.Aur, trusted); config.DiffMenu && len(untrusted) > 0 {
+		pkgbuildNumberMenu(untrusted, remoteNamesCache)
+		toDiff, err = diffNumberMenu(untrusted, remoteNamesCache)
 		if err != nil {
 			return err
 		}
+	}
 
-		if len(toDiff) > 0 {
-			err = showPkgbuildDiffs(toDiff, cloned)
-			if err != nil {
-				return err
-			}
+	// risky build files and new maintainers are always reviewed
+	for _, base := range toReview {
+		if !containsBase(toDiff, base) {
+			toDiff = append(toDiff, base)
+		}
+	}
+
+	if len(toDiff) > 0 {
+		err = showPkgbuildDiffs(toDiff, cloned)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -290,6 +342,9 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 		return err
 	}
 
+	// the bases whose hooks failed are left out, the rest is still installed
+	hookErr := runPostDownloadHooks(do)
+
 	srcinfos, err = parseSrcinfoFiles(do.Aur, true)
 	if err != nil {
 		return err
@@ -413,17 +468,28 @@ func install(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, ignoreProvide
 
 	go exitOnError(completion.Update(alpmHandle, config.AURURL, config.Runtime.CompletionPath, config.CompletionInterval, false))
 
+	j := newJournal(config.Runtime.JournalPath, cmdArgs, dp, do, incompatible, conflicts)
+	if errJournal := j.save(); errJournal != nil {
+		fmt.Fprintln(os.Stderr, errJournal)
+	}
+
 	err = downloadPkgbuildsSources(do.Aur, incompatible)
 	if err != nil {
+		text.Warnln(gotext.Get("Run %s to continue the transaction", cyan("yay --resume")))
 		return err
 	}
 
-	err = buildInstallPkgbuilds(cmdArgs, alpmHandle, dp, do, srcinfos, incompatible, conflicts)
+	err = buildInstallPkgbuilds(cmdArgs, alpmHandle, dp, do, srcinfos, incompatible, conflicts, j)
 	if err != nil {
+		text.Warnln(gotext.Get("Run %s to continue the transaction", cyan("yay --resume")))
 		return err
 	}
 
-	return nil
+	if err = j.remove(); err != nil {
+		return err
+	}
+
+	return hookErr
 }
 
 func removeMake(do *dep.Order) error {
@@ -540,23 +606,59 @@ func earlyRefresh(cmdArgs *settings.Arguments) error {
 	return show(passToPacman(arguments))
 }
 
-func getIncompatible(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo, alpmHandle *alpm.Handle) (stringset.StringSet, error) {
+// incompatibleBases returns the bases with a .SRCINFO in srcinfos that
+// can't be built for arch
+func incompatibleBases(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo, alpmArch string) stringset.StringSet {
 	incompatible := make(stringset.StringSet)
-	basesMap := make(map[string]dep.Base)
-	alpmArch, err := alpmHandle.Arch()
-	if err != nil {
-		return nil, err
-	}
 
 nextpkg:
 	for _, base := range bases {
-		for _, arch := range srcinfos[base.Pkgbase()].Arch {
+		srcinfo, ok := srcinfos[base.Pkgbase()]
+		if !ok {
+			continue
+		}
+
+		for _, arch := range srcinfo.Arch {
 			if arch == "any" || arch == alpmArch {
 				continue nextpkg
 			}
 		}
 
 		incompatible.Set(base.Pkgbase())
+	}
+
+	return incompatible
+}
+
+// planIncompatible returns the bases a plan builds with --ignorearch. The
+// PKGBUILDs aren't downloaded for a plan, so only the bases downloaded by an
+// earlier run are checked.
+func planIncompatible(bases []dep.Base, alpmHandle *alpm.Handle) stringset.StringSet {
+	alpmArch, err := alpmHandle.Arch()
+	if err != nil {
+		return make(stringset.StringSet)
+	}
+
+	srcinfos := make(map[string]*gosrc.Srcinfo)
+	for _, base := range bases {
+		srcinfo, err := gosrc.ParseFile(filepath.Join(config.BuildDir, base.Pkgbase(), ".SRCINFO"))
+		if err == nil {
+			srcinfos[base.Pkgbase()] = srcinfo
+		}
+	}
+
+	return incompatibleBases(bases, srcinfos, alpmArch)
+}
+
+func getIncompatible(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo, alpmHandle *alpm.Handle) (stringset.StringSet, error) {
+	alpmArch, err := alpmHandle.Arch()
+	if err != nil {
+		return nil, err
+	}
+
+	incompatible := incompatibleBases(bases, srcinfos, alpmArch)
+	basesMap := make(map[string]dep.Base)
+	for _, base := range bases {
 		basesMap[base.Pkgbase()] = base
 	}
 
@@ -806,6 +908,11 @@ func updatePkgbuildSeenRef(bases []dep.Base) error {
 		if err != nil {
 			errMulti.Add(err)
 		}
+
+		err = gitUpdateSeenMaintainer(config.BuildDir, pkg, base[0].Maintainer)
+		if err != nil {
+			errMulti.Add(err)
+		}
 	}
 	return errMulti.Return()
 }
@@ -838,7 +945,7 @@ func showPkgbuildDiffs(bases []dep.Base, cloned stringset.StringSet) error {
 
 		args := []string{
 			"diff",
-			start + "..HEAD@{upstream}", "--src-prefix",
+			start + ".." + gitMergeTarget(pkg), "--src-prefix",
 			dir + "/", "--dst-prefix", dir + "/", "--", ".", ":(exclude).SRCINFO"}
 		if text.UseColor {
 			args = append(args, "--color=always")
@@ -960,7 +1067,7 @@ func downloadPkgbuilds(bases []dep.Base, toSkip stringset.StringSet, buildDir st
 			return
 		}
 
-		clone, err := gitDownload(config.AURURL+"/"+pkg+".git", buildDir, pkg)
+		clone, err := gitDownload(query.Client.CloneURL(pkg), buildDir, pkg)
 		if err != nil {
 			errs.Add(err)
 			return
@@ -992,34 +1099,65 @@ func downloadPkgbuilds(bases []dep.Base, toSkip stringset.StringSet, buildDir st
 	return cloned, errs.Return()
 }
 
-func downloadPkgbuildsSources(bases []dep.Base, incompatible stringset.StringSet) (err error) {
-	for _, base := range bases {
+func downloadPkgbuildsSources(bases []dep.Base, incompatible stringset.StringSet) error {
+	jobs := intrange.Max(config.DownloadJobs, 1)
+	downloaded := 0
+	failed := 0
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	var errs multierror.MultiError
+	sem := make(chan struct{}, jobs)
+
+	download := func(base dep.Base) {
+		defer wg.Done()
+		defer func() { <-sem }()
+
 		pkg := base.Pkgbase()
 		dir := filepath.Join(config.BuildDir, pkg)
-		args := []string{"--verifysource", "-Ccf"}
-
-		if incompatible.Get(pkg) {
-			args = append(args, "--ignorearch")
+		var out io.Writer
+		var buf *bytes.Buffer
+		if jobs > 1 {
+			buf = new(bytes.Buffer)
+			out = buf
 		}
 
-		err = show(passToMakepkg(dir, args...))
+		err := runBuildCmd(sourcesCmd(dir, incompatible.Get(pkg)), pkg, out)
+
+		mux.Lock()
+		defer mux.Unlock()
+
 		if err != nil {
-			return errors.New(gotext.Get("error downloading sources: %s", cyan(base.String())))
+			failed++
+			if buf != nil {
+				_, _ = os.Stdout.Write(buf.Bytes())
+			}
+			errs.Add(errors.New(gotext.Get("error downloading sources: %s", cyan(base.String()))))
+			return
+		}
+
+		downloaded++
+		if buf != nil {
+			text.OperationInfoln(gotext.Get("Downloaded sources (%d/%d): %s", downloaded, len(bases), cyan(base.String())))
 		}
 	}
 
-	return
-}
+	for _, base := range bases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go download(base)
+	}
 
-func buildInstallPkgbuilds(
-	cmdArgs *settings.Arguments,
-	alpmHandle *alpm.Handle,
-	dp *dep.Pool,
-	do *dep.Order,
-	srcinfos map[string]*gosrc.Srcinfo,
-	incompatible stringset.StringSet,
-	conflicts stringset.MapStringSet) error {
+	wg.Wait()
 
+	if jobs > 1 && len(bases) > 0 {
+		text.OperationInfoln(gotext.Get("Sources: %d downloaded, %d failed", downloaded, failed))
+	}
+
+	return errs.Return()
+}
+
+// installArguments creates the arguments to pass to pacman -U for built packages
+func installArguments(cmdArgs *settings.Arguments) *settings.Arguments {
 	arguments := cmdArgs.Copy()
 	arguments.ClearTargets()
 	arguments.Op = "U"
@@ -1032,8 +1170,24 @@ func buildInstallPkgbuilds(
 	arguments.DelArg("u", "sysupgrade")
 	arguments.DelArg("w", "downloadonly")
 
+	return arguments
+}
+
+func buildInstallPkgbuilds(
+	cmdArgs *settings.Arguments,
+	alpmHandle *alpm.Handle,
+	dp *dep.Pool,
+	do *dep.Order,
+	srcinfos map[string]*gosrc.Srcinfo,
+	incompatible stringset.StringSet,
+	conflicts stringset.MapStringSet,
+	j *journal) error {
+
+	arguments := installArguments(cmdArgs)
+
 	deps := make([]string, 0)
 	exp := make([]string, 0)
+	names := make([]string, 0)
 	oldConfirm := config.NoConfirm
 	config.NoConfirm = true
 
@@ -1053,7 +1207,19 @@ func buildInstallPkgbuilds(
 			return nil
 		}
 
-		if errShow := show(passToPacman(arguments)); errShow != nil {
+		installArgs := arguments
+		if config.LocalRepo {
+			if errRepo := addToLocalRepo(arguments.Targets); errRepo != nil {
+				return errRepo
+			}
+			if errRepo := refreshLocalRepo(); errRepo != nil {
+				return errRepo
+			}
+
+			installArgs = localRepoArguments(arguments, names)
+		}
+
+		if errShow := show(passToPacman(installArgs)); errShow != nil {
 			return errShow
 		}
 
@@ -1074,111 +1240,151 @@ func buildInstallPkgbuilds(
 		arguments.ClearTargets()
 		deps = make([]string, 0)
 		exp = make([]string, 0)
+		names = make([]string, 0)
 		config.NoConfirm = true
 		return nil
 	}
 
-	for _, base := range do.Aur {
-		pkg := base.Pkgbase()
-		dir := filepath.Join(config.BuildDir, pkg)
-		built := true
+	if config.Chroot {
+		if err = ensureChroot(); err != nil {
+			return err
+		}
+	}
 
-		satisfied := true
-	all:
-		for _, pkg := range base {
-			for _, deps := range [3][]string{pkg.Depends, pkg.MakeDepends, pkg.CheckDepends} {
-				for _, dep := range deps {
-					if _, errSatisfier := dp.LocalDB.PkgCache().FindSatisfier(dep); errSatisfier != nil {
-						satisfied = false
-						text.Warnln(gotext.Get("%s not satisfied, flushing install queue", dep))
-						break all
-					}
-				}
-			}
+	if config.LocalRepo {
+		if err = checkLocalRepo(); err != nil {
+			return err
 		}
+	}
 
-		if !satisfied || !config.BatchInstall {
-			err = doInstall()
-			if err != nil {
-				return err
-			}
+	jobs := intrange.Max(config.BuildJobs, 1)
+	scheduler := newBuildScheduler(do)
+	// buffered so running builds never block once the loop returned early
+	results := make(chan *buildResult, jobs)
+	queued := make([]string, 0)
+	finished := make(map[string]*buildResult)
+	running := 0
+	summary := &buildSummary{}
+	var vcsMux sync.Mutex
+
+	// package files built in this transaction, injected into the chroot of
+	// the bases depending on them
+	built := make(map[string][]string)
+	injectFor := func(base dep.Base) []string {
+		pkgs := make([]string, 0)
+		for depBase := range scheduler.allDeps(base.Pkgbase()) {
+			pkgs = append(pkgs, built[depBase]...)
 		}
 
-		srcinfo := srcinfos[pkg]
+		return pkgs
+	}
 
-		args := []string{"--nobuild", "-fC"}
+	build := func(base dep.Base, inject []string) {
+		res := &buildResult{base: base}
 
-		if incompatible.Get(pkg) {
-			args = append(args, "--ignorearch")
+		var out io.Writer
+		if jobs > 1 {
+			res.output = new(bytes.Buffer)
+			out = res.output
 		}
 
-		// pkgver bump
-		if err = show(passToMakepkg(dir, args...)); err != nil {
-			return errors.New(gotext.Get("error making: %s", base.String()))
+		// reuse the packages of a resumed transaction
+		if j.state(base.Pkgbase()) == journalBuilt {
+			var ok bool
+			if res.pkgdests, res.pkgVersion, ok = builtPackages(base); ok {
+				fmt.Fprintln(buildOutput(out), text.SprintWarn(gotext.Get("%s already made -- skipping build",
+					cyan(base.Pkgbase()+"-"+res.pkgVersion))))
+				results <- res
+				return
+			}
 		}
 
-		pkgdests, pkgVersion, errList := parsePackageList(dir)
-		if errList != nil {
-			return errList
+		if jobs > 1 {
+			text.OperationInfoln(gotext.Get("Building: %s", cyan(base.String())))
 		}
 
-		isExplicit := false
-		for _, b := range base {
-			isExplicit = isExplicit || dp.Explicit.Get(b.Name)
+		res.pkgdests, res.pkgVersion, res.upToDate, res.err = buildPkgbuild(cmdArgs, dp, base, incompatible, inject, out)
+		results <- res
+	}
+
+	flush := func() error {
+		if errInstall := doInstall(); errInstall != nil {
+			return errInstall
 		}
-		if config.ReBuild == "no" || (config.ReBuild == "yes" && !isExplicit) {
-			for _, split := range base {
-				pkgdest, ok := pkgdests[split.Name]
-				if !ok {
-					return errors.New(gotext.Get("could not find PKGDEST for: %s", split.Name))
-				}
 
-				if _, errStat := os.Stat(pkgdest); os.IsNotExist(errStat) {
-					built = false
-				} else if errStat != nil {
-					return errStat
-				}
+		for _, pkg := range queued {
+			if res := finished[pkg]; !res.upToDate {
+				runPostHooks(hookPostInstall, res.base, res.pkgVersion, built[pkg], nil)
 			}
-		} else {
-			built = false
 		}
 
-		if cmdArgs.ExistsArg("needed") {
-			installed := true
-			for _, split := range base {
-				if alpmpkg := dp.LocalDB.Pkg(split.Name); alpmpkg == nil || alpmpkg.Version() != pkgVersion {
-					installed = false
-				}
+		j.set(journalInstalled, queued...)
+		summary.succeeded(queued...)
+		scheduler.installed.Extend(queued...)
+		queued = queued[:0]
+		return nil
+	}
+
+	for !scheduler.done() || running > 0 {
+		for running < jobs {
+			base, ok := scheduler.next()
+			if !ok {
+				break
 			}
 
-			if installed {
-				err = show(passToMakepkg(dir, "-c", "--nobuild", "--noextract", "--ignorearch"))
-				if err != nil {
-					return errors.New(gotext.Get("error making: %s", err))
+			running++
+			go build(base, injectFor(base))
+		}
+
+		if running == 0 {
+			// everything left waits on bases that are built but not installed
+			if len(queued) > 0 {
+				if err = flush(); err != nil {
+					return err
 				}
+				continue
+			}
 
-				fmt.Fprintln(os.Stdout, gotext.Get("%s is up to date -- skipping", cyan(pkg+"-"+pkgVersion)))
+			// the remaining bases depend on each other, fall back to the
+			// resolved order
+			base := scheduler.force()
+			running++
+			go build(base, injectFor(base))
+		}
+
+		res := <-results
+		running--
+		printBuildOutput(res)
+
+		if res.err != nil {
+			j.set(journalFailed, res.base.Pkgbase())
+			var hookErr *hookError
+			if config.KeepGoing || errors.As(res.err, &hookErr) {
+				text.Errorln(res.err)
+				summary.fail(res.base.Pkgbase(), res.err)
+				for _, base := range scheduler.skipDependants(res.base.Pkgbase()) {
+					summary.skip(base.Pkgbase(), res.base.Pkgbase())
+				}
 				continue
 			}
-		}
 
-		if built {
-			err = show(passToMakepkg(dir, "-c", "--nobuild", "--noextract", "--ignorearch"))
-			if err != nil {
-				return errors.New(gotext.Get("error making: %s", err))
+			for ; running > 0; running-- {
+				printBuildOutput(<-results)
 			}
 
-			text.Warnln(gotext.Get("%s already made -- skipping build", cyan(pkg+"-"+pkgVersion)))
-		} else {
-			args := []string{"-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}
+			return res.err
+		}
 
-			if incompatible.Get(pkg) {
-				args = append(args, "--ignorearch")
-			}
+		pkg := res.base.Pkgbase()
+		queued = append(queued, pkg)
+		finished[pkg] = res
+		j.set(journalBuilt, pkg)
+		currentHistory.noteBuilt(pkg)
 
-			if errMake := show(passToMakepkg(dir, args...)); errMake != nil {
-				return errors.New(gotext.Get("error making: %s", base.String()))
-			}
+		built[pkg] = packageFiles(res.base, res.pkgdests)
+
+		if res.upToDate {
+			continue
 		}
 
 		// conflicts have been checked so answer y for them
@@ -1187,7 +1393,7 @@ func buildInstallPkgbuilds(
 			uask := alpm.QuestionType(ask) | alpm.QuestionTypeConflictPkg
 			cmdArgs.Options["ask"].Set(fmt.Sprint(uask))
 		} else {
-			for _, split := range base {
+			for _, split := range res.base {
 				if _, ok := conflicts[split.Name]; ok {
 					config.NoConfirm = false
 					break
@@ -1196,7 +1402,7 @@ func buildInstallPkgbuilds(
 		}
 
 		doAddTarget := func(name string, optional bool) error {
-			pkgdest, ok := pkgdests[name]
+			pkgdest, ok := res.pkgdests[name]
 			if !ok {
 				if optional {
 					return nil
@@ -1217,6 +1423,7 @@ func buildInstallPkgbuilds(
 			}
 
 			arguments.AddTarget(pkgdest)
+			names = append(names, name)
 			if cmdArgs.ExistsArg("asdeps", "asdep") {
 				deps = append(deps, name)
 			} else if cmdArgs.ExistsArg("asexplicit", "asexp") {
@@ -1228,7 +1435,7 @@ func buildInstallPkgbuilds(
 			return nil
 		}
 
-		for _, split := range base {
+		for _, split := range res.base {
 			if errAdd := doAddTarget(split.Name, false); errAdd != nil {
 				return errAdd
 			}
@@ -1238,17 +1445,30 @@ func buildInstallPkgbuilds(
 			}
 		}
 
-		var mux sync.Mutex
 		var wg sync.WaitGroup
-		for _, pkg := range base {
+		for _, split := range res.base {
 			wg.Add(1)
-			go updateVCSData(config.Runtime.VCSPath, pkg.Name, srcinfo.Source, &mux, &wg)
+			go updateVCSData(config.Runtime.VCSPath, split.Name, srcinfos[pkg].Source, &vcsMux, &wg)
 		}
 
 		wg.Wait()
+
+		if !config.BatchInstall {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
 	}
 
-	err = doInstall()
+	err = flush()
 	config.NoConfirm = oldConfirm
+
+	if config.KeepGoing || summary.failed > 0 {
+		summary.print()
+		if err == nil {
+			err = summary.err()
+		}
+	}
+
 	return err
 }
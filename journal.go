@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	alpm "github.com/Jguer/go-alpm"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// States a pkgbase can be in during a transaction
+const (
+	journalPending   = "pending"
+	journalBuilt     = "built"
+	journalInstalled = "installed"
+	journalFailed    = "failed"
+)
+
+// journal records the progress of an AUR transaction so it can be resumed
+// with --resume after a failure
+type journal struct {
+	path string
+	mux  sync.Mutex
+
+	Targets      []string            `json:"targets"`
+	Args         *settings.Arguments `json:"args"`
+	Explicit     []string            `json:"explicit"`
+	Incompatible []string            `json:"incompatible"`
+	Conflicts    []string            `json:"conflicts"`
+	Order        []*journalBase      `json:"order"`
+}
+
+type journalBase struct {
+	Pkgbase  string   `json:"pkgbase"`
+	State    string   `json:"state"`
+	Depends  []string `json:"depends"`
+	Packages dep.Base `json:"packages"`
+}
+
+func newJournal(path string, cmdArgs *settings.Arguments, dp *dep.Pool, do *dep.Order,
+	incompatible stringset.StringSet, conflicts stringset.MapStringSet) *journal {
+	j := &journal{
+		path:         path,
+		Targets:      cmdArgs.Targets,
+		Args:         cmdArgs.Copy(),
+		Explicit:     dp.Explicit.ToSlice(),
+		Incompatible: incompatible.ToSlice(),
+		Conflicts:    make([]string, 0, len(conflicts)),
+		Order:        make([]*journalBase, 0, len(do.Aur)),
+	}
+
+	for name := range conflicts {
+		j.Conflicts = append(j.Conflicts, name)
+	}
+
+	for _, base := range do.Aur {
+		j.Order = append(j.Order, &journalBase{
+			Pkgbase:  base.Pkgbase(),
+			State:    journalPending,
+			Depends:  do.BaseDeps[base.Pkgbase()].ToSlice(),
+			Packages: base,
+		})
+	}
+
+	return j
+}
+
+func loadJournal(path string) (*journal, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errors.New(gotext.Get("no transaction to resume"))
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	j := &journal{path: path}
+	if err = json.NewDecoder(file).Decode(j); err != nil {
+		return nil, errors.New(gotext.Get("failed to read transaction journal '%s': %s", path, err))
+	}
+
+	return j, nil
+}
+
+func (j *journal) save() error {
+	marshalled, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(j.path, marshalled, 0o644)
+}
+
+func (j *journal) remove() error {
+	err := os.Remove(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (j *journal) state(pkgbase string) string {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	for _, base := range j.Order {
+		if base.Pkgbase == pkgbase {
+			return base.State
+		}
+	}
+
+	return ""
+}
+
+// set updates the state of pkgbases and writes the journal to disk. Failing
+// to write the journal only loses the ability to resume, so it is not fatal.
+func (j *journal) set(state string, pkgbases ...string) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	set := stringset.FromSlice(pkgbases)
+	for _, base := range j.Order {
+		if set.Get(base.Pkgbase) {
+			base.State = state
+		}
+	}
+
+	if err := j.save(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// builtPackages returns the package files of a pkgbase that was built by the
+// interrupted transaction if all of them are still in PKGDEST
+func builtPackages(base dep.Base) (pkgdests map[string]string, pkgVersion string, ok bool) {
+	pkgdests, pkgVersion, err := parsePackageList(filepath.Join(config.BuildDir, base.Pkgbase()))
+	if err != nil {
+		return nil, "", false
+	}
+
+	for _, split := range base {
+		pkgdest, exists := pkgdests[split.Name]
+		if !exists {
+			return nil, "", false
+		}
+
+		if _, err := os.Stat(pkgdest); err != nil {
+			return nil, "", false
+		}
+	}
+
+	return pkgdests, pkgVersion, true
+}
+
+// resumeInstall continues the transaction recorded in the journal from the
+// first base that was not installed
+func resumeInstall(alpmHandle *alpm.Handle) error {
+	j, err := loadJournal(config.Runtime.JournalPath)
+	if err != nil {
+		return err
+	}
+
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	installed := make(stringset.StringSet)
+	do := &dep.Order{
+		Aur:      make([]dep.Base, 0),
+		Runtime:  make(stringset.StringSet),
+		BaseDeps: make(stringset.MapStringSet),
+	}
+	toDownload := make([]dep.Base, 0)
+
+	for _, base := range j.Order {
+		if base.State == journalInstalled {
+			installed.Set(base.Pkgbase)
+			continue
+		}
+
+		do.Aur = append(do.Aur, base.Packages)
+		if base.State != journalBuilt {
+			toDownload = append(toDownload, base.Packages)
+		}
+	}
+
+	if len(do.Aur) == 0 {
+		fmt.Println(gotext.Get(" there is nothing to do"))
+		return j.remove()
+	}
+
+	// dependencies installed by the interrupted transaction are satisfied
+	for _, base := range j.Order {
+		for _, depBase := range base.Depends {
+			if !installed.Get(depBase) {
+				do.BaseDeps.Add(base.Pkgbase, depBase)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(do.Aur))
+	for _, base := range do.Aur {
+		names = append(names, base.String())
+	}
+	text.OperationInfoln(gotext.Get("Resuming transaction: %s", cyan(strings.Join(names, " "))))
+
+	dp := &dep.Pool{
+		Explicit: stringset.FromSlice(j.Explicit),
+		LocalDB:  localDB,
+	}
+
+	conflicts := make(stringset.MapStringSet)
+	for _, name := range j.Conflicts {
+		conflicts[name] = make(stringset.StringSet)
+	}
+
+	incompatible := stringset.FromSlice(j.Incompatible)
+
+	srcinfos, err := parseSrcinfoFiles(do.Aur, true)
+	if err != nil {
+		return err
+	}
+
+	if err = downloadPkgbuildsSources(toDownload, incompatible); err != nil {
+		return err
+	}
+
+	if err = buildInstallPkgbuilds(j.Args, alpmHandle, dp, do, srcinfos, incompatible, conflicts, j); err != nil {
+		return err
+	}
+
+	return j.remove()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "transaction.json")
+
+	cmdArgs := settings.MakeArguments()
+	cmdArgs.Op = "S"
+	_ = cmdArgs.AddArg("needed")
+	cmdArgs.AddTarget("b")
+
+	do := &dep.Order{
+		Aur:      []dep.Base{makeBase("a"), makeBase("b")},
+		BaseDeps: make(stringset.MapStringSet),
+	}
+	do.BaseDeps.Add("b", "a")
+	dp := &dep.Pool{Explicit: stringset.Make("b")}
+
+	j := newJournal(path, cmdArgs, dp, do, make(stringset.StringSet), make(stringset.MapStringSet))
+	j.set(journalInstalled, "a")
+	j.set(journalFailed, "b")
+
+	loaded, err := loadJournal(path)
+	assert.NoError(t, err)
+	assert.Equal(t, journalInstalled, loaded.state("a"))
+	assert.Equal(t, journalFailed, loaded.state("b"))
+	assert.Equal(t, []string{"a"}, loaded.Order[1].Depends)
+	assert.Equal(t, "b", loaded.Order[1].Packages[0].Name)
+	assert.True(t, loaded.Args.ExistsArg("needed"))
+	assert.Equal(t, []string{"b"}, loaded.Explicit)
+
+	assert.NoError(t, loaded.remove())
+	_, err = loadJournal(path)
+	assert.Error(t, err)
+}
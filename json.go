@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	alpm "github.com/Jguer/go-alpm"
+	rpc "github.com/mikkeloscar/aur"
+)
+
+// The --json forms of search results, package info and upgrades. Every field
+// is always present, the ones that don't apply to a package are null and
+// lists are empty instead of null.
+
+type jsonSearchResult struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Source is "aur" or the name of the sync db
+	Source     string   `json:"source"`
+	Votes      *int     `json:"votes"`
+	Popularity *float64 `json:"popularity"`
+	OutOfDate  *int     `json:"outofdate"`
+	Installed  bool     `json:"installed"`
+	// InstalledVersion is the local version, which may differ from Version
+	InstalledVersion *string `json:"installedversion"`
+}
+
+type jsonInfo struct {
+	Source       string   `json:"source"`
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Description  string   `json:"description"`
+	URL          string   `json:"url"`
+	PackageBase  string   `json:"packagebase"`
+	Licenses     []string `json:"licenses"`
+	Groups       []string `json:"groups"`
+	Keywords     []string `json:"keywords"`
+	Provides     []string `json:"provides"`
+	Depends      []string `json:"depends"`
+	MakeDepends  []string `json:"makedepends"`
+	CheckDepends []string `json:"checkdepends"`
+	OptDepends   []string `json:"optdepends"`
+	Conflicts    []string `json:"conflicts"`
+	Replaces     []string `json:"replaces"`
+
+	// AUR only
+	AURURL         *string  `json:"aururl"`
+	Maintainer     *string  `json:"maintainer"`
+	Votes          *int     `json:"votes"`
+	Popularity     *float64 `json:"popularity"`
+	OutOfDate      *int     `json:"outofdate"`
+	FirstSubmitted *int     `json:"firstsubmitted"`
+	LastModified   *int     `json:"lastmodified"`
+
+	// repo only
+	Packager      *string `json:"packager"`
+	BuildDate     *int64  `json:"builddate"`
+	DownloadSize  *int64  `json:"downloadsize"`
+	InstalledSize *int64  `json:"installedsize"`
+
+	Installed        bool    `json:"installed"`
+	InstalledVersion *string `json:"installedversion"`
+}
+
+type jsonUpgrade struct {
+	Name       string `json:"name"`
+	OldVersion string `json:"oldversion"`
+	// NewVersion is "latest-commit" for devel packages
+	NewVersion string `json:"newversion"`
+	// Repository is "aur" or the name of the sync db
+	Repository string `json:"repository"`
+	Devel      bool   `json:"devel"`
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// installedVersion returns the version of name in localDB, or nil when it
+// isn't installed
+func installedVersion(localDB *alpm.DB, name string) *string {
+	if localDB == nil {
+		return nil
+	}
+
+	pkg := localDB.Pkg(name)
+	if pkg == nil {
+		return nil
+	}
+
+	version := pkg.Version()
+	return &version
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func depStrings(deps alpm.DependList) []string {
+	list := make([]string, 0)
+	_ = deps.ForEach(func(dep alpm.Depend) error {
+		list = append(list, dep.String())
+		return nil
+	})
+	return list
+}
+
+func aurSearchResult(pkg *rpc.Pkg, installed *string) jsonSearchResult {
+	result := jsonSearchResult{
+		Name:             pkg.Name,
+		Version:          pkg.Version,
+		Description:      pkg.Description,
+		Source:           "aur",
+		Votes:            &pkg.NumVotes,
+		Popularity:       &pkg.Popularity,
+		Installed:        installed != nil,
+		InstalledVersion: installed,
+	}
+
+	if pkg.OutOfDate != 0 {
+		result.OutOfDate = &pkg.OutOfDate
+	}
+
+	return result
+}
+
+func repoSearchResult(pkg *alpm.Package, installed *string) jsonSearchResult {
+	return jsonSearchResult{
+		Name:             pkg.Name(),
+		Version:          pkg.Version(),
+		Description:      pkg.Description(),
+		Source:           pkg.DB().Name(),
+		Installed:        installed != nil,
+		InstalledVersion: installed,
+	}
+}
+
+func aurInfoRecord(pkg *rpc.Pkg, installed *string) jsonInfo {
+	aurURL := config.AURURL + "/packages/" + pkg.Name
+	info := jsonInfo{
+		Source:           "aur",
+		Name:             pkg.Name,
+		Version:          pkg.Version,
+		Description:      pkg.Description,
+		URL:              pkg.URL,
+		PackageBase:      pkg.PackageBase,
+		Licenses:         nonNil(pkg.License),
+		Groups:           nonNil(pkg.Groups),
+		Keywords:         nonNil(pkg.Keywords),
+		Provides:         nonNil(pkg.Provides),
+		Depends:          nonNil(pkg.Depends),
+		MakeDepends:      nonNil(pkg.MakeDepends),
+		CheckDepends:     nonNil(pkg.CheckDepends),
+		OptDepends:       nonNil(pkg.OptDepends),
+		Conflicts:        nonNil(pkg.Conflicts),
+		Replaces:         nonNil(pkg.Replaces),
+		AURURL:           &aurURL,
+		Maintainer:       &pkg.Maintainer,
+		Votes:            &pkg.NumVotes,
+		Popularity:       &pkg.Popularity,
+		FirstSubmitted:   &pkg.FirstSubmitted,
+		LastModified:     &pkg.LastModified,
+		Installed:        installed != nil,
+		InstalledVersion: installed,
+	}
+
+	if pkg.OutOfDate != 0 {
+		info.OutOfDate = &pkg.OutOfDate
+	}
+
+	return info
+}
+
+func repoInfoRecord(pkg *alpm.Package, installed *string) jsonInfo {
+	packager := pkg.Packager()
+	buildDate := pkg.BuildDate().Unix()
+	size := pkg.Size()
+	isize := pkg.ISize()
+
+	return jsonInfo{
+		Source:           pkg.DB().Name(),
+		Name:             pkg.Name(),
+		Version:          pkg.Version(),
+		Description:      pkg.Description(),
+		URL:              pkg.URL(),
+		PackageBase:      pkg.Base(),
+		Licenses:         nonNil(pkg.Licenses().Slice()),
+		Groups:           nonNil(pkg.Groups().Slice()),
+		Keywords:         []string{},
+		Provides:         depStrings(pkg.Provides()),
+		Depends:          depStrings(pkg.Depends()),
+		MakeDepends:      depStrings(pkg.MakeDepends()),
+		CheckDepends:     depStrings(pkg.CheckDepends()),
+		OptDepends:       depStrings(pkg.OptionalDepends()),
+		Conflicts:        depStrings(pkg.Conflicts()),
+		Replaces:         depStrings(pkg.Replaces()),
+		Packager:         &packager,
+		BuildDate:        &buildDate,
+		DownloadSize:     &size,
+		InstalledSize:    &isize,
+		Installed:        installed != nil,
+		InstalledVersion: installed,
+	}
+}
+
+func upgradeRecord(u *upgrade) jsonUpgrade {
+	record := jsonUpgrade{
+		Name:       u.Name,
+		OldVersion: u.LocalVersion,
+		NewVersion: u.RemoteVersion,
+		Repository: u.Repository,
+	}
+
+	// devel upgrades are listed under a "devel" repository
+	if u.Repository == "devel" {
+		record.Repository = "aur"
+		record.Devel = true
+	}
+
+	return record
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAURSearchResultJSON(t *testing.T) {
+	pkg := &rpc.Pkg{Name: "yay", Version: "10.0.4-1", Description: "AUR helper", NumVotes: 1500, Popularity: 42.5}
+	installed := "10.0.3-1"
+
+	var buf bytes.Buffer
+	assert.NoError(t, printJSON(&buf, []jsonSearchResult{
+		aurSearchResult(pkg, &installed),
+		aurSearchResult(&rpc.Pkg{Name: "yay-bin", Version: "10.0.4-1", OutOfDate: 1600000000}, nil),
+	}))
+	assert.Equal(t, `[
+  {
+    "name": "yay",
+    "version": "10.0.4-1",
+    "description": "AUR helper",
+    "source": "aur",
+    "votes": 1500,
+    "popularity": 42.5,
+    "outofdate": null,
+    "installed": true,
+    "installedversion": "10.0.3-1"
+  },
+  {
+    "name": "yay-bin",
+    "version": "10.0.4-1",
+    "description": "",
+    "source": "aur",
+    "votes": 0,
+    "popularity": 0,
+    "outofdate": 1600000000,
+    "installed": false,
+    "installedversion": null
+  }
+]
+`, buf.String())
+}
+
+func TestUpgradeRecord(t *testing.T) {
+	assert.Equal(t, jsonUpgrade{"linux", "5.8.9-1", "5.8.10-1", "core", false},
+		upgradeRecord(&upgrade{"linux", "core", "5.8.9-1", "5.8.10-1"}))
+	assert.Equal(t, jsonUpgrade{"yay-git", "10.0.3-1", "latest-commit", "aur", true},
+		upgradeRecord(&upgrade{"yay-git", "devel", "10.0.3-1", "latest-commit"}))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// localRepoDB is the repo-add database of the local repository
+func localRepoDB() string {
+	return filepath.Join(config.LocalRepoDir, config.LocalRepoName+".db.tar.gz")
+}
+
+// checkLocalRepo makes sure pacman knows the local repository, otherwise
+// the packages added to it could not be installed with pacman -S
+func checkLocalRepo() error {
+	if config.LocalRepoName == "" {
+		return errors.New(gotext.Get("no local repository name set"))
+	}
+
+	if config.Runtime.PacmanConf.Repository(config.LocalRepoName) == nil {
+		return errors.New(gotext.Get("local repository %s is not configured in %s", config.LocalRepoName, config.PacmanConf))
+	}
+
+	return os.MkdirAll(config.LocalRepoDir, 0o755)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// localRepoFile returns where the package file pkgdest is kept in the local
+// repository
+func localRepoFile(pkgdest string) string {
+	return filepath.Join(config.LocalRepoDir, filepath.Base(pkgdest))
+}
+
+// addToLocalRepo copies package files into the local repository and adds
+// them to its database. repo-add -R deletes the files of the versions they
+// replace.
+func addToLocalRepo(pkgdests []string) error {
+	files := make([]string, 0, len(pkgdests))
+
+	for _, pkgdest := range pkgdests {
+		file := localRepoFile(pkgdest)
+		if file != pkgdest {
+			if err := copyFile(pkgdest, file); err != nil {
+				return errors.New(gotext.Get("failed to copy %s to the local repository: %s", pkgdest, err))
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	text.OperationInfoln(gotext.Get("Adding packages to local repository: %s", cyan(config.LocalRepoName)))
+
+	args := append([]string{"-R", localRepoDB()}, files...)
+	if err := show(exec.Command("repo-add", args...)); err != nil {
+		return errors.New(gotext.Get("error adding packages to local repository: %s", err))
+	}
+
+	return nil
+}
+
+// localRepoConf is the pacman.conf used to refresh the sync database of the
+// local repository on its own
+func localRepoConf() string {
+	return filepath.Join(config.BuildDir, "localrepo-pacman.conf")
+}
+
+// localRepoPacmanConf returns a pacman.conf with the options of the system
+// one that matter to a refresh and the local repository as its only repo
+func localRepoPacmanConf() string {
+	pacmanConf := config.Runtime.PacmanConf
+	var b strings.Builder
+
+	b.WriteString("[options]\n")
+	fmt.Fprintf(&b, "RootDir = %s\n", pacmanConf.RootDir)
+	fmt.Fprintf(&b, "DBPath = %s\n", pacmanConf.DBPath)
+	fmt.Fprintf(&b, "GPGDir = %s\n", pacmanConf.GPGDir)
+	if pacmanConf.Architecture != "" {
+		fmt.Fprintf(&b, "Architecture = %s\n", pacmanConf.Architecture)
+	}
+	if len(pacmanConf.SigLevel) > 0 {
+		fmt.Fprintf(&b, "SigLevel = %s\n", strings.Join(pacmanConf.SigLevel, " "))
+	}
+
+	fmt.Fprintf(&b, "\n[%s]\n", config.LocalRepoName)
+	if repo := pacmanConf.Repository(config.LocalRepoName); repo != nil {
+		if len(repo.SigLevel) > 0 {
+			fmt.Fprintf(&b, "SigLevel = %s\n", strings.Join(repo.SigLevel, " "))
+		}
+		for _, server := range repo.Servers {
+			fmt.Fprintf(&b, "Server = %s\n", server)
+		}
+	}
+
+	return b.String()
+}
+
+// refreshLocalRepoArgs is the pacman -Sy that refreshes the sync database of
+// the local repository and leaves the others alone, refreshing those would
+// turn the install into a partial upgrade
+func refreshLocalRepoArgs() []string {
+	args := make([]string, 0)
+	args = append(args, config.SudoBin)
+	args = append(args, strings.Fields(config.SudoFlags)...)
+	return append(args, config.PacmanBin, "-Sy", "--config", localRepoConf())
+}
+
+// refreshLocalRepo makes the packages added to the local repository
+// installable with pacman -S
+func refreshLocalRepo() error {
+	if err := ioutil.WriteFile(localRepoConf(), []byte(localRepoPacmanConf()), 0o644); err != nil {
+		return err
+	}
+
+	args := refreshLocalRepoArgs()
+	waitLock(config.Runtime.PacmanConf.DBPath)
+	if err := show(exec.Command(args[0], args[1:]...)); err != nil {
+		return errors.New(gotext.Get("error refreshing local repository: %s", err))
+	}
+
+	return nil
+}
+
+// localRepoArguments turns the pacman -U arguments for built packages into
+// a pacman -S of the same packages from the local repository
+func localRepoArguments(arguments *settings.Arguments, names []string) *settings.Arguments {
+	repoArgs := arguments.Copy()
+	repoArgs.Op = "S"
+	repoArgs.ClearTargets()
+
+	for _, name := range names {
+		repoArgs.AddTarget(config.LocalRepoName + "/" + name)
+	}
+
+	return repoArgs
+}
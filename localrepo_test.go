@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	pacmanconf "github.com/Morganamilo/go-pacmanconf"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/settings"
+)
+
+func TestLocalRepoArguments(t *testing.T) {
+	oldConfig := config
+	defer func() { config = oldConfig }()
+	config = settings.MakeConfig()
+	config.LocalRepoName = "aur"
+
+	arguments := settings.MakeArguments()
+	arguments.Op = "U"
+	_ = arguments.AddArg("needed")
+	arguments.AddTarget("/build/foo/foo-1.0-1-x86_64.pkg.tar.zst", "/build/foo/foo-docs-1.0-1-any.pkg.tar.zst")
+
+	repoArgs := localRepoArguments(arguments, []string{"foo", "foo-docs"})
+
+	assert.Equal(t, "S", repoArgs.Op)
+	assert.True(t, repoArgs.ExistsArg("needed"))
+	assert.False(t, repoArgs.ExistsArg("y", "refresh"))
+	assert.Equal(t, []string{"aur/foo", "aur/foo-docs"}, repoArgs.Targets)
+
+	// the arguments of the host install are left alone
+	assert.Equal(t, "U", arguments.Op)
+	assert.Equal(t, []string{"/build/foo/foo-1.0-1-x86_64.pkg.tar.zst", "/build/foo/foo-docs-1.0-1-any.pkg.tar.zst"}, arguments.Targets)
+}
+
+func TestLocalRepoPacmanConf(t *testing.T) {
+	oldConfig := config
+	defer func() { config = oldConfig }()
+	config = settings.MakeConfig()
+	config.LocalRepoName = "aur"
+	config.Runtime = &settings.Runtime{PacmanConf: &pacmanconf.Config{
+		RootDir:      "/",
+		DBPath:       "/var/lib/pacman/",
+		GPGDir:       "/etc/pacman.d/gnupg/",
+		Architecture: "x86_64",
+		SigLevel:     []string{"Required", "DatabaseOptional"},
+		Repos: []pacmanconf.Repository{
+			{Name: "core", Servers: []string{"https://mirror/core/os/x86_64"}},
+			{Name: "aur", Servers: []string{"file:///srv/repo"}, SigLevel: []string{"Optional", "TrustAll"}},
+		},
+	}}
+
+	assert.Equal(t, `[options]
+RootDir = /
+DBPath = /var/lib/pacman/
+GPGDir = /etc/pacman.d/gnupg/
+Architecture = x86_64
+SigLevel = Required DatabaseOptional
+
+[aur]
+SigLevel = Optional TrustAll
+Server = file:///srv/repo
+`, localRepoPacmanConf())
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	alpm "github.com/Jguer/go-alpm"
 	pacmanconf "github.com/Morganamilo/go-pacmanconf"
 	"github.com/leonelquinteros/gotext"
 
+	"github.com/Jguer/yay/v10/pkg/query"
 	"github.com/Jguer/yay/v10/pkg/settings"
 	"github.com/Jguer/yay/v10/pkg/text"
 )
@@ -61,6 +63,42 @@ func initVCS(vcsFilePath string) error {
 	return nil
 }
 
+// newAURClient returns the client of the AUR source the config asks for: a
+// metadata dump with aurmetadata, the RPC behind the info cache otherwise.
+// Build files are always fetched from AURURL.
+func newAURClient(cmdArgs *settings.Arguments) query.AURClient {
+	if config.AURMetadata != "" {
+		return &query.DumpClient{Path: config.AURMetadata, GitURL: config.AURURL, GitBin: config.GitBin}
+	}
+
+	// upgrades, installs and trust checks always ask for the current info
+	// and only fall back to the cache when the AUR can't be reached
+	var ttl time.Duration
+	if showsAURInfo(cmdArgs) {
+		ttl = time.Duration(config.AURCacheTime) * time.Minute
+	}
+
+	return &query.CacheClient{
+		AURClient: &query.RPCClient{URL: config.AURURL},
+		Path:      config.Runtime.AURCachePath,
+		TTL:       ttl,
+		Refresh:   config.Runtime.RefreshAUR,
+	}
+}
+
+// showsAURInfo returns whether a command only shows AUR info, so it may be
+// served from the cache without asking
+func showsAURInfo(cmdArgs *settings.Arguments) bool {
+	switch cmdArgs.Op {
+	case "S", "sync":
+		return cmdArgs.ExistsArg("i", "info") && !cmdArgs.ExistsArg("u", "sysupgrade")
+	case "P", "show":
+		return cmdArgs.ExistsArg("s", "stats")
+	}
+
+	return false
+}
+
 func initBuildDir() error {
 	if _, err := os.Stat(config.BuildDir); os.IsNotExist(err) {
 		if err = os.MkdirAll(config.BuildDir, 0700); err != nil {
@@ -182,6 +220,10 @@ func main() {
 	config.Runtime = runtime
 	exitOnError(initConfig(runtime.ConfigPath))
 	exitOnError(cmdArgs.ParseCommandLine(config))
+	if cmdArgs.ExistsArg("print-plan", "graph") || config.Runtime.JSON {
+		// stdout is left to the machine readable output
+		text.SetOutput(os.Stderr)
+	}
 	if config.Runtime.SaveConfig {
 		errS := config.SaveConfig(runtime.ConfigPath)
 		if errS != nil {
@@ -189,8 +231,13 @@ func main() {
 		}
 	}
 	config.ExpandEnv()
+	query.Client = &pinnedClient{AURClient: newAURClient(cmdArgs)}
 	exitOnError(initBuildDir())
 	exitOnError(initVCS(runtime.VCSPath))
+	savedPins, err = loadPins(runtime.PinsPath)
+	exitOnError(err)
+	savedTrust, err = loadTrust(runtime.TrustPath)
+	exitOnError(err)
 	config.Runtime.AlpmHandle, config.Runtime.PacmanConf, err = initAlpm(cmdArgs, config.PacmanConf)
 	exitOnError(err)
 	exitOnError(handleCmd(cmdArgs, config.Runtime.AlpmHandle))
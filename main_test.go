@@ -2,9 +2,11 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/Jguer/yay/v10/pkg/query"
 	"github.com/Jguer/yay/v10/pkg/settings"
 )
 
@@ -59,3 +61,39 @@ func TestInitAlpm(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, true, check)
 }
+
+func TestNewAURClient(t *testing.T) {
+	oldConfig := config
+	defer func() { config = oldConfig }()
+	config = settings.MakeConfig()
+	config.Runtime = &settings.Runtime{AURCachePath: "/cache/aur-info.json"}
+
+	args := func(op string, flags ...string) *settings.Arguments {
+		cmdArgs := settings.MakeArguments()
+		cmdArgs.Op = op
+		for _, flag := range flags {
+			_ = cmdArgs.AddArg(flag)
+		}
+		return cmdArgs
+	}
+
+	ttl := func(cmdArgs *settings.Arguments) time.Duration {
+		client, ok := newAURClient(cmdArgs).(*query.CacheClient)
+		assert.True(t, ok)
+		return client.TTL
+	}
+
+	assert.Equal(t, time.Hour, ttl(args("S", "i")))
+	assert.Equal(t, time.Hour, ttl(args("P", "s")))
+
+	// the rest asks every time and only falls back to the cache
+	assert.Zero(t, ttl(args("S", "u")))
+	assert.Zero(t, ttl(args("S", "i", "u")))
+	assert.Zero(t, ttl(args("S")))
+	assert.Zero(t, ttl(args("Q", "u")))
+	assert.Zero(t, ttl(args("P", "u")))
+	assert.Zero(t, ttl(args("Y", "gendb")))
+
+	config.AURMetadata = "/srv/packages-meta-ext-v1.json.gz"
+	assert.IsType(t, &query.DumpClient{}, newAURClient(args("S", "i")))
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	alpm "github.com/Jguer/go-alpm"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// manifestEntry is a line of a package manifest: [source/]name[=version]
+// where source is a sync database or aur
+type manifestEntry struct {
+	Source  string
+	Name    string
+	Version string
+}
+
+func (e manifestEntry) target() string {
+	if e.Source == "" {
+		return e.Name
+	}
+
+	return e.Source + "/" + e.Name
+}
+
+func (e manifestEntry) String() string {
+	if e.Version == "" {
+		return e.target()
+	}
+
+	return e.target() + "=" + e.Version
+}
+
+// parseManifest reads a manifest. Empty lines and everything after a # are
+// ignored.
+func parseManifest(r io.Reader) ([]manifestEntry, error) {
+	entries := make([]manifestEntry, 0)
+	seen := make(stringset.StringSet)
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var entry manifestEntry
+		if i := strings.Index(line, "="); i >= 0 {
+			line, entry.Version = line[:i], line[i+1:]
+		}
+
+		if i := strings.Index(line, "/"); i >= 0 {
+			entry.Source, entry.Name = line[:i], line[i+1:]
+		} else {
+			entry.Name = line
+		}
+
+		if entry.Name == "" || strings.ContainsAny(entry.Name, " \t") {
+			return nil, errors.New(gotext.Get("invalid manifest entry on line %d: %s", n, scanner.Text()))
+		}
+
+		if seen.Get(entry.Name) {
+			return nil, errors.New(gotext.Get("duplicate manifest entry on line %d: %s", n, entry.Name))
+		}
+		seen.Set(entry.Name)
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// writeManifest writes the entries sorted by name
+func writeManifest(w io.Writer, entries []manifestEntry) error {
+	sorted := make([]manifestEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for _, entry := range sorted {
+		if _, err := fmt.Fprintln(w, entry.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportManifest writes the explicitly installed packages to path, or to
+// stdout for -. Packages that aren't in any sync database are listed as aur
+// packages.
+func exportManifest(path string, alpmHandle *alpm.Handle) error {
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	syncDB, err := alpmHandle.SyncDBs()
+	if err != nil {
+		return err
+	}
+
+	_, remoteNames, err := query.GetPackageNamesBySource(alpmHandle)
+	if err != nil {
+		return err
+	}
+	remoteNamesCache := stringset.FromSlice(remoteNames)
+
+	entries := make([]manifestEntry, 0)
+	_ = localDB.PkgCache().ForEach(func(pkg alpm.Package) error {
+		if pkg.Reason() != alpm.PkgReasonExplicit {
+			return nil
+		}
+
+		entry := manifestEntry{Source: "aur", Name: pkg.Name()}
+		if !remoteNamesCache.Get(pkg.Name()) {
+			_ = syncDB.ForEach(func(db alpm.DB) error {
+				if entry.Source == "aur" && db.Pkg(pkg.Name()) != nil {
+					entry.Source = db.Name()
+				}
+				return nil
+			})
+		}
+
+		entries = append(entries, entry)
+		return nil
+	})
+
+	if path == "-" {
+		return writeManifest(os.Stdout, entries)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err = writeManifest(file, entries); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	text.OperationInfoln(gotext.Get("Exported %d packages to %s", len(entries), cyan(path)))
+	return nil
+}
+
+// syncManifest reconciles the system with the manifest at path: missing
+// packages are installed and listed packages are marked as explicitly
+// installed. With prune, explicit packages that aren't listed are marked as
+// dependencies and removed unless something still requires them.
+func syncManifest(cmdArgs *settings.Arguments, path string, alpmHandle *alpm.Handle, prune bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	entries, err := parseManifest(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	listed := make(stringset.StringSet)
+	missing := make([]manifestEntry, 0)
+	arguments := cmdArgs.CopyGlobal()
+	arguments.Op = "S"
+	_ = arguments.AddArg("needed")
+
+	for _, entry := range entries {
+		listed.Set(entry.Name)
+		if localDB.Pkg(entry.Name) == nil {
+			missing = append(missing, entry)
+			arguments.AddTarget(entry.target())
+		}
+	}
+
+	// nothing is built or installed when a pinned version can't be installed
+	available, err := availableVersions(missing, alpmHandle)
+	if err != nil {
+		return err
+	}
+
+	if msgs := unavailablePins(missing, available); len(msgs) > 0 {
+		for _, msg := range msgs {
+			text.Errorln(msg)
+		}
+
+		return errors.New(gotext.Get("the manifest pins versions that can't be installed"))
+	}
+
+	if len(arguments.Targets) > 0 {
+		text.OperationInfoln(gotext.Get("Installing %d missing packages", len(arguments.Targets)))
+		if err = install(arguments, alpmHandle, false); err != nil {
+			return err
+		}
+	}
+
+	// the transaction changed the local database
+	alpmHandle, err = initAlpmHandle(config.Runtime.PacmanConf, alpmHandle)
+	if err != nil {
+		return err
+	}
+	config.Runtime.AlpmHandle = alpmHandle
+
+	if localDB, err = alpmHandle.LocalDB(); err != nil {
+		return err
+	}
+
+	toExplicit := make([]string, 0)
+	unlisted := make([]string, 0)
+	mismatched := make([]string, 0)
+
+	for _, entry := range entries {
+		pkg := localDB.Pkg(entry.Name)
+		if pkg == nil {
+			mismatched = append(mismatched, gotext.Get("%s is not installed", cyan(entry.Name)))
+			continue
+		}
+
+		if pkg.Reason() != alpm.PkgReasonExplicit {
+			toExplicit = append(toExplicit, entry.Name)
+		}
+
+		if entry.Version != "" && pkg.Version() != entry.Version {
+			mismatched = append(mismatched,
+				gotext.Get("%s is at version %s, the manifest wants %s", cyan(entry.Name), pkg.Version(), entry.Version))
+		}
+	}
+
+	_ = localDB.PkgCache().ForEach(func(pkg alpm.Package) error {
+		if pkg.Reason() == alpm.PkgReasonExplicit && !listed.Get(pkg.Name()) {
+			unlisted = append(unlisted, pkg.Name())
+		}
+		return nil
+	})
+
+	if err = asexp(cmdArgs, toExplicit); err != nil {
+		return err
+	}
+
+	if len(unlisted) > 0 {
+		if prune {
+			if err = pruneUnlisted(cmdArgs, unlisted); err != nil {
+				return err
+			}
+		} else {
+			text.Warnln(gotext.Get("Explicitly installed packages not in the manifest:"), cyan(strings.Join(unlisted, " ")))
+		}
+	}
+
+	if len(mismatched) > 0 {
+		for _, msg := range mismatched {
+			text.Errorln(msg)
+		}
+
+		return errors.New(gotext.Get("the system does not match the manifest"))
+	}
+
+	return nil
+}
+
+// availableVersions returns the versions the pinned entries would be
+// installed at, taken from their sync database or the AUR
+func availableVersions(entries []manifestEntry, alpmHandle *alpm.Handle) (map[string]string, error) {
+	versions := make(map[string]string)
+	aurNames := make([]string, 0)
+
+	syncDB, err := alpmHandle.SyncDBs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Version == "" {
+			continue
+		}
+
+		if entry.Source != "aur" {
+			_ = syncDB.ForEach(func(db alpm.DB) error {
+				if _, ok := versions[entry.Name]; ok || (entry.Source != "" && entry.Source != db.Name()) {
+					return nil
+				}
+
+				if pkg := db.Pkg(entry.Name); pkg != nil {
+					versions[entry.Name] = pkg.Version()
+				}
+				return nil
+			})
+		}
+
+		if _, ok := versions[entry.Name]; !ok && (entry.Source == "" || entry.Source == "aur") {
+			aurNames = append(aurNames, entry.Name)
+		}
+	}
+
+	if len(aurNames) == 0 {
+		return versions, nil
+	}
+
+	info, err := aurInfo(aurNames, &query.AURWarnings{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range info {
+		versions[pkg.Name] = pkg.Version
+	}
+
+	return versions, nil
+}
+
+// unavailablePins describes the entries pinned to a version other than the
+// available one. Entries that aren't available at all are left to the
+// install to report.
+func unavailablePins(entries []manifestEntry, available map[string]string) []string {
+	msgs := make([]string, 0)
+
+	for _, entry := range entries {
+		version, ok := available[entry.Name]
+		if entry.Version == "" || !ok || version == entry.Version {
+			continue
+		}
+
+		msgs = append(msgs,
+			gotext.Get("%s is available at version %s, the manifest wants %s", cyan(entry.Name), version, entry.Version))
+	}
+
+	return msgs
+}
+
+// pruneUnlisted removes the packages nothing else requires, along with the
+// packages only they require. The rest is marked as dependencies once the
+// removal went through, so declining it at the pacman prompt leaves every
+// install reason alone.
+func pruneUnlisted(cmdArgs *settings.Arguments, pkgs []string) error {
+	localDB, err := config.Runtime.AlpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	requiredBy := make(map[string][]string, len(pkgs))
+	for _, name := range pkgs {
+		if pkg := localDB.Pkg(name); pkg != nil {
+			requiredBy[name] = pkg.ComputeRequiredBy()
+		}
+	}
+
+	toRemove, toDeps := unrequiredPackages(requiredBy)
+	if len(toRemove) > 0 {
+		removeArguments := cmdArgs.CopyGlobal()
+		removeArguments.Op = "R"
+		_ = removeArguments.AddArg("s")
+		removeArguments.AddTarget(toRemove...)
+
+		if err = show(passToPacman(removeArguments)); err != nil {
+			return err
+		}
+	}
+
+	return asdeps(cmdArgs, toDeps)
+}
+
+// unrequiredPackages splits packages by what requires them into the ones that
+// are required by nothing but each other and the ones something else still
+// requires. Both are sorted.
+func unrequiredPackages(requiredBy map[string][]string) (unrequired, required []string) {
+	removable := make(stringset.StringSet)
+
+	for changed := true; changed; {
+		changed = false
+		for name, reqs := range requiredBy {
+			if removable.Get(name) {
+				continue
+			}
+
+			free := true
+			for _, req := range reqs {
+				free = free && removable.Get(req)
+			}
+
+			if free {
+				removable.Set(name)
+				changed = true
+			}
+		}
+	}
+
+	unrequired = make([]string, 0, len(removable))
+	required = make([]string, 0, len(requiredBy)-len(removable))
+	for name := range requiredBy {
+		if removable.Get(name) {
+			unrequired = append(unrequired, name)
+		} else {
+			required = append(required, name)
+		}
+	}
+
+	sort.Strings(unrequired)
+	sort.Strings(required)
+	return unrequired, required
+}
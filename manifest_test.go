@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/leonelquinteros/gotext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest := `# dev box
+core/bash
+aur/yay-bin=10.0.4-1   # pinned
+vim
+
+`
+	entries, err := parseManifest(strings.NewReader(manifest))
+	assert.NoError(t, err)
+	assert.Equal(t, []manifestEntry{
+		{Source: "core", Name: "bash"},
+		{Source: "aur", Name: "yay-bin", Version: "10.0.4-1"},
+		{Name: "vim"},
+	}, entries)
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeManifest(&buf, entries))
+	assert.Equal(t, "core/bash\nvim\naur/yay-bin=10.0.4-1\n", buf.String())
+	assert.Equal(t, "yay-bin", entries[1].Name, "writeManifest must not reorder the entries")
+}
+
+func TestUnavailablePins(t *testing.T) {
+	entries := []manifestEntry{
+		{Source: "core", Name: "bash", Version: "5.0.018-1"},
+		{Source: "aur", Name: "yay-bin", Version: "10.0.4-1"},
+		{Name: "vim"},
+		{Name: "gone", Version: "1.0-1"},
+	}
+	available := map[string]string{"bash": "5.0.018-1", "yay-bin": "10.1.0-1", "vim": "8.2.1-1"}
+
+	assert.Equal(t, []string{
+		gotext.Get("%s is available at version %s, the manifest wants %s", cyan("yay-bin"), "10.1.0-1", "10.0.4-1"),
+	}, unavailablePins(entries, available))
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	_, err := parseManifest(strings.NewReader("bash\ncore/bash\n"))
+	assert.Error(t, err)
+
+	_, err = parseManifest(strings.NewReader("aur/=1.0\n"))
+	assert.Error(t, err)
+}
+
+func TestUnrequiredPackages(t *testing.T) {
+	unrequired, required := unrequiredPackages(map[string][]string{
+		"editor":   {},
+		"plugin":   {"editor"},
+		"lib":      {"plugin", "base-app"},
+		"tool":     nil,
+		"toolkit":  {"tool", "plugin"},
+		"shared":   {"tool", "base-app"},
+		"base-app": {"listed"},
+	})
+
+	assert.Equal(t, []string{"editor", "plugin", "tool", "toolkit"}, unrequired)
+	assert.Equal(t, []string{"base-app", "lib", "shared"}, required)
+}
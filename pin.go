@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	alpm "github.com/Jguer/go-alpm"
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+	rpc "github.com/mikkeloscar/aur"
+
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// pinStore maps a pkgbase to the AUR git commit it is pinned to
+type pinStore map[string]string
+
+func loadPins(path string) (pinStore, error) {
+	pins := make(pinStore)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return pins, nil
+	} else if err != nil {
+		return nil, errors.New(gotext.Get("failed to open pin file '%s': %s", path, err))
+	}
+
+	if err = json.Unmarshal(data, &pins); err != nil {
+		return nil, errors.New(gotext.Get("failed to read pin file '%s': %s", path, err))
+	}
+
+	return pins, nil
+}
+
+func (p pinStore) save(path string) error {
+	data, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0o644)
+}
+
+func isPinned(pkgbase string) bool {
+	_, ok := savedPins[pkgbase]
+	return ok
+}
+
+// gitMergeTarget returns what the build directory of a pkgbase should be
+// moved to: its pinned commit or the upstream HEAD
+func gitMergeTarget(pkgbase string) string {
+	if commit, ok := savedPins[pkgbase]; ok {
+		return commit
+	}
+
+	return "HEAD@{upstream}"
+}
+
+// pinnedClient serves the packages of pinned bases as they are at the pinned
+// commit, so dependencies and versions are resolved against what is built
+type pinnedClient struct {
+	query.AURClient
+
+	mux sync.Mutex
+}
+
+func (c *pinnedClient) Info(names []string) ([]rpc.Pkg, error) {
+	info, err := c.AURClient.Info(names)
+	if err != nil {
+		return info, err
+	}
+
+	resolved := info[:0]
+	for i := range info {
+		if !isPinned(info[i].PackageBase) {
+			resolved = append(resolved, info[i])
+			continue
+		}
+
+		srcinfo, err := c.pinnedSrcinfo(info[i].PackageBase)
+		if err != nil {
+			return nil, err
+		}
+
+		// split packages dropped at the pinned commit don't exist
+		if setPinnedInfo(&info[i], srcinfo) {
+			resolved = append(resolved, info[i])
+		}
+	}
+
+	return resolved, nil
+}
+
+// pinnedSrcinfo parses the .SRCINFO of a pkgbase at its pinned commit,
+// fetching the build directory when it doesn't have the commit yet
+func (c *pinnedClient) pinnedSrcinfo(pkgbase string) (*gosrc.Srcinfo, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	commit := savedPins[pkgbase]
+	data, err := gitShow(pkgbase, commit, ".SRCINFO")
+	if err != nil {
+		if _, err = gitDownload(c.CloneURL(pkgbase), config.BuildDir, pkgbase); err != nil {
+			return nil, err
+		}
+		if data, err = gitShow(pkgbase, commit, ".SRCINFO"); err != nil {
+			return nil, errors.New(gotext.Get("unable to read %s at pinned commit %s: %s", pkgbase, shortCommit(commit), err))
+		}
+	}
+
+	return gosrc.Parse(string(data))
+}
+
+// setPinnedInfo replaces the metadata of pkg with the one of its split
+// package in srcinfo. It returns false when srcinfo doesn't build pkg.
+func setPinnedInfo(pkg *rpc.Pkg, srcinfo *gosrc.Srcinfo) bool {
+	split, err := srcinfo.SplitPackage(pkg.Name)
+	if err != nil {
+		return false
+	}
+
+	pkg.Version = srcinfo.Version()
+	pkg.Description = split.Pkgdesc
+	pkg.URL = split.URL
+	pkg.License = split.License
+	pkg.Depends = archValues(split.Depends)
+	pkg.OptDepends = archValues(split.OptDepends)
+	pkg.Provides = archValues(split.Provides)
+	pkg.Conflicts = archValues(split.Conflicts)
+	pkg.Replaces = archValues(split.Replaces)
+	pkg.MakeDepends = archValues(srcinfo.MakeDepends)
+	pkg.CheckDepends = archValues(srcinfo.CheckDepends)
+
+	return true
+}
+
+// pinPkgbase resolves the pkgbase of an installed or AUR package
+func pinPkgbase(name string, alpmHandle *alpm.Handle) (string, error) {
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return "", err
+	}
+
+	if pkg := localDB.Pkg(name); pkg != nil && pkg.Base() != "" {
+		return pkg.Base(), nil
+	}
+
+	warnings := &query.AURWarnings{}
+	info, err := query.AURInfo([]string{name}, warnings, config.RequestSplitN)
+	if err != nil {
+		return "", err
+	}
+
+	if len(info) == 0 {
+		return "", errors.New(gotext.Get("%s is not an AUR package", name))
+	}
+
+	return info[0].PackageBase, nil
+}
+
+// pinPackages pins every target of the form pkg[@commit]. Without a commit
+// the package is pinned to the commit currently checked out in its build
+// directory, which is the one it was last built from.
+func pinPackages(targets []string, alpmHandle *alpm.Handle) error {
+	for _, target := range targets {
+		name, commit := target, "HEAD"
+		if i := strings.LastIndex(target, "@"); i >= 0 {
+			name, commit = target[:i], target[i+1:]
+		}
+
+		pkgbase, err := pinPkgbase(name, alpmHandle)
+		if err != nil {
+			return err
+		}
+
+		if _, err = gitDownload(query.Client.CloneURL(pkgbase), config.BuildDir, pkgbase); err != nil {
+			return err
+		}
+
+		stdout, _, err := capture(passToGit(filepath.Join(config.BuildDir, pkgbase),
+			"rev-parse", "--verify", "--quiet", commit+"^{commit}"))
+		if err != nil {
+			return errors.New(gotext.Get("%s is not a commit of %s", commit, pkgbase))
+		}
+
+		savedPins[pkgbase] = stdout
+		text.OperationInfoln(gotext.Get("Pinned %s to %s", cyan(pkgbase), bold(shortCommit(stdout))))
+	}
+
+	return savedPins.save(config.Runtime.PinsPath)
+}
+
+func unpinPackages(targets []string, alpmHandle *alpm.Handle) error {
+	for _, name := range targets {
+		pkgbase := name
+		if _, ok := savedPins[pkgbase]; !ok {
+			var err error
+			if pkgbase, err = pinPkgbase(name, alpmHandle); err != nil {
+				return err
+			}
+		}
+
+		if _, ok := savedPins[pkgbase]; !ok {
+			return errors.New(gotext.Get("%s is not pinned", name))
+		}
+
+		delete(savedPins, pkgbase)
+		text.OperationInfoln(gotext.Get("Unpinned %s", cyan(pkgbase)))
+	}
+
+	return savedPins.save(config.Runtime.PinsPath)
+}
+
+// printPins lists the pins and how many commits upstream is ahead of each
+func printPins() error {
+	pkgbases := make([]string, 0, len(savedPins))
+	for pkgbase := range savedPins {
+		pkgbases = append(pkgbases, pkgbase)
+	}
+	sort.Strings(pkgbases)
+
+	for _, pkgbase := range pkgbases {
+		commit := savedPins[pkgbase]
+		behind := red(gotext.Get("unknown"))
+
+		if _, err := gitDownload(query.Client.CloneURL(pkgbase), config.BuildDir, pkgbase); err != nil {
+			text.Warnln(err)
+		} else if stdout, _, err := capture(passToGit(filepath.Join(config.BuildDir, pkgbase),
+			"rev-list", "--count", commit+"..HEAD@{upstream}")); err == nil {
+			if n, _ := strconv.Atoi(stdout); n == 0 {
+				behind = green(gotext.Get("up to date"))
+			} else {
+				behind = magenta(gotext.Get("%d commits behind", n))
+			}
+		}
+
+		fmt.Printf("%s %s %s\n", bold(pkgbase), cyan(shortCommit(commit)), behind)
+	}
+
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 10 {
+		return commit[:10]
+	}
+
+	return commit
+}
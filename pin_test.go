@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-pins")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pins.json")
+	pins, err := loadPins(path)
+	assert.NoError(t, err)
+	assert.Empty(t, pins)
+
+	pins["yay"] = "0123456789abcdef"
+	assert.NoError(t, pins.save(path))
+
+	oldPins := savedPins
+	defer func() { savedPins = oldPins }()
+	savedPins, err = loadPins(path)
+	assert.NoError(t, err)
+
+	assert.True(t, isPinned("yay"))
+	assert.False(t, isPinned("yay-bin"))
+	assert.Equal(t, "0123456789abcdef", gitMergeTarget("yay"))
+	assert.Equal(t, "HEAD@{upstream}", gitMergeTarget("yay-bin"))
+}
+
+func TestSetPinnedInfo(t *testing.T) {
+	srcinfo, err := gosrc.Parse(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 2
+	arch = x86_64
+	makedepends = go
+	depends = glibc
+
+pkgname = foo
+	pkgdesc = foo at the pinned commit
+
+pkgname = foo-docs
+	depends = foo
+`)
+	assert.NoError(t, err)
+
+	pkg := rpc.Pkg{
+		Name:        "foo",
+		PackageBase: "foo",
+		Version:     "2.0-1",
+		Depends:     []string{"glibc", "openssl"},
+		MakeDepends: []string{"rust"},
+	}
+	assert.True(t, setPinnedInfo(&pkg, srcinfo))
+	assert.Equal(t, "1.0-2", pkg.Version)
+	assert.Equal(t, "foo at the pinned commit", pkg.Description)
+	assert.Equal(t, []string{"glibc"}, pkg.Depends)
+	assert.Equal(t, []string{"go"}, pkg.MakeDepends)
+
+	docs := rpc.Pkg{Name: "foo-docs", PackageBase: "foo"}
+	assert.True(t, setPinnedInfo(&docs, srcinfo))
+	assert.Equal(t, []string{"foo"}, docs.Depends)
+
+	// split packages added after the pinned commit aren't built by it
+	assert.False(t, setPinnedInfo(&rpc.Pkg{Name: "foo-extra", PackageBase: "foo"}, srcinfo))
+}
@@ -0,0 +1,146 @@
+// Package aurhttp sends the HTTP requests to the AUR. It limits how many of
+// them run at once and retries the ones that fail on the network, with a 5xx
+// response or because the AUR rate limited them.
+package aurhttp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// Client sends requests to the AUR. Every attempt of a request has Timeout to
+// finish, and a failed attempt is retried up to Retries times, waiting Backoff
+// before the first retry and twice as long before every following one.
+type Client struct {
+	HTTP    *http.Client
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+	// MaxWait is the longest a rate limited request waits to be retried
+	MaxWait time.Duration
+
+	slots chan struct{}
+}
+
+// New returns a client that runs at most maxConcurrent requests at once
+func New(maxConcurrent int) *Client {
+	return &Client{
+		HTTP:    http.DefaultClient,
+		Timeout: 30 * time.Second,
+		Retries: 3,
+		Backoff: time.Second,
+		MaxWait: 30 * time.Second,
+		slots:   make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Default is the client all AUR traffic goes through
+var Default = New(5)
+
+// StatusError is a response with an unexpected status code
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("invalid status code: %d", e.Code)
+}
+
+// RateLimitError is a request the AUR refused to answer until RetryAfter
+// passed
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return gotext.Get("AUR rate limited, retry after %s", e.RetryAfter)
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as a date
+func retryAfter(header string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now).Round(time.Second)
+	}
+
+	return 0
+}
+
+func (c *Client) get(url string) ([]byte, error) {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &RateLimitError{retryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	case resp.StatusCode != http.StatusOK:
+		return nil, &StatusError{url, resp.StatusCode}
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Get returns the body of a successful response to a GET request of url.
+// Network errors, 5xx responses and rate limits are retried, other status
+// codes fail with a StatusError right away.
+func (c *Client) Get(url string) ([]byte, error) {
+	delay := c.Backoff
+
+	for attempt := 0; ; attempt++ {
+		body, err := c.get(url)
+		if err == nil {
+			return body, nil
+		}
+
+		wait := delay
+		switch e := err.(type) {
+		case *StatusError:
+			if e.Code < 500 {
+				return nil, err
+			}
+		case *RateLimitError:
+			if e.RetryAfter > c.MaxWait {
+				return nil, err
+			}
+			if e.RetryAfter > wait {
+				wait = e.RetryAfter
+			}
+			if attempt < c.Retries {
+				text.Warnln(gotext.Get("AUR rate limited, retrying in %s", wait))
+			}
+		}
+
+		if attempt >= c.Retries {
+			return nil, err
+		}
+
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
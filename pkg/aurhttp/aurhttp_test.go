@@ -0,0 +1,80 @@
+package aurhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testClient() *Client {
+	c := New(2)
+	c.Backoff = time.Millisecond
+	c.MaxWait = time.Second
+	return c
+}
+
+func TestGetRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	body, err := testClient().Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestGetErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/limited":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	c := testClient()
+
+	_, err := c.Get(server.URL + "/missing")
+	assert.Equal(t, &StatusError{server.URL + "/missing", http.StatusNotFound}, err)
+	assert.Equal(t, int32(1), calls)
+
+	_, err = c.Get(server.URL + "/limited")
+	assert.Equal(t, &RateLimitError{time.Hour}, err)
+	assert.EqualError(t, err, "AUR rate limited, retry after 1h0m0s")
+	assert.Equal(t, int32(2), calls)
+
+	_, err = c.Get(server.URL + "/down")
+	assert.EqualError(t, err, "invalid status code: 502")
+	assert.Equal(t, int32(2+1+c.Retries), calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 7, 24, 22, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 2*time.Minute, retryAfter("120", now))
+	assert.Equal(t, 90*time.Second, retryAfter("Fri, 24 Jul 2020 22:01:30 GMT", now))
+	assert.Equal(t, time.Duration(0), retryAfter("", now))
+	assert.Equal(t, time.Duration(0), retryAfter("Fri, 24 Jul 2020 21:00:00 GMT", now))
+}
@@ -2,9 +2,8 @@ package completion
 
 import (
 	"bufio"
-	"fmt"
+	"bytes"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"path"
@@ -13,6 +12,8 @@ import (
 	"time"
 
 	alpm "github.com/Jguer/go-alpm"
+
+	"github.com/Jguer/yay/v10/pkg/aurhttp"
 )
 
 // Show provides completion info for shells
@@ -70,16 +71,12 @@ func createAURList(aurURL string, out io.Writer) error {
 		return err
 	}
 	u.Path = path.Join(u.Path, "packages.gz")
-	resp, err := http.Get(u.String())
+	body, err := aurhttp.Default.Get(u.String())
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("invalid status code: %d", resp.StatusCode)
-	}
 
-	scanner := bufio.NewScanner(resp.Body)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
 
 	scanner.Scan()
 	for scanner.Scan() {
@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
+
+	"github.com/Jguer/yay/v10/pkg/aurhttp"
 )
 
 const samplePackageResp = `
@@ -31,6 +33,14 @@ lumina	AUR
 eternallands-sound	AUR
 `
 
+// noBackoff retries failed requests right away until the returned function
+// is called
+func noBackoff() func() {
+	backoff := aurhttp.Default.Backoff
+	aurhttp.Default.Backoff = 0
+	return func() { aurhttp.Default.Backoff = backoff }
+}
+
 func Test_createAURList(t *testing.T) {
 	defer gock.Off()
 
@@ -47,9 +57,11 @@ func Test_createAURList(t *testing.T) {
 
 func Test_createAURListHTTPError(t *testing.T) {
 	defer gock.Off()
+	defer noBackoff()()
 
 	gock.New("https://aur.archlinux.org").
 		Get("/packages.gz").
+		Persist().
 		ReplyError(errors.New("Not available"))
 	out := &bytes.Buffer{}
 	err := createAURList("https://aur.archlinux.org", out)
@@ -58,9 +70,11 @@ func Test_createAURListHTTPError(t *testing.T) {
 
 func Test_createAURListStatusError(t *testing.T) {
 	defer gock.Off()
+	defer noBackoff()()
 
 	gock.New("https://aur.archlinux.org").
 		Get("/packages.gz").
+		Persist().
 		Reply(503).
 		BodyString(samplePackageResp)
 	out := &bytes.Buffer{}
@@ -159,7 +159,7 @@ func (dp *Pool) CheckConflicts(useAsk, noConfirm bool) (stringset.MapStringSet,
 			}
 			str = strings.TrimSuffix(str, ",")
 
-			fmt.Println(str)
+			fmt.Fprintln(text.Out(), str)
 		}
 	}
 
@@ -173,7 +173,7 @@ func (dp *Pool) CheckConflicts(useAsk, noConfirm bool) (stringset.MapStringSet,
 			}
 			str = strings.TrimSuffix(str, ",")
 
-			fmt.Println(str)
+			fmt.Fprintln(text.Out(), str)
 		}
 	}
 
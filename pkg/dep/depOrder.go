@@ -14,6 +14,9 @@ type Order struct {
 	Aur     []Base
 	Repo    []*alpm.Package
 	Runtime stringset.StringSet
+	// BaseDeps maps a pkgbase to the pkgbases in Aur that have to be
+	// built and installed before it can be built.
+	BaseDeps stringset.MapStringSet
 }
 
 func makeOrder() *Order {
@@ -21,6 +24,7 @@ func makeOrder() *Order {
 		make([]Base, 0),
 		make([]*alpm.Package, 0),
 		make(stringset.StringSet),
+		make(stringset.MapStringSet),
 	}
 }
 
@@ -61,6 +65,10 @@ func (do *Order) orderPkgAur(pkg *rpc.Pkg, dp *Pool, runtime bool) {
 				do.orderPkgAur(aurPkg, dp, runtime && i == 0)
 			}
 
+			if base := do.findSatisfierBase(dep); base != "" && base != pkg.PackageBase {
+				do.BaseDeps.Add(pkg.PackageBase, base)
+			}
+
 			repoPkg := dp.findSatisfierRepo(dep)
 			if repoPkg != nil {
 				do.orderPkgRepo(repoPkg, dp, runtime && i == 0)
@@ -78,6 +86,20 @@ func (do *Order) orderPkgAur(pkg *rpc.Pkg, dp *Pool, runtime bool) {
 	do.Aur = append(do.Aur, Base{pkg})
 }
 
+// findSatisfierBase returns the pkgbase of an already ordered AUR package
+// satisfying dep.
+func (do *Order) findSatisfierBase(dep string) string {
+	for _, base := range do.Aur {
+		for _, pkg := range base {
+			if satisfiesAur(dep, pkg) {
+				return base.Pkgbase()
+			}
+		}
+	}
+
+	return ""
+}
+
 func (do *Order) orderPkgRepo(pkg *alpm.Package, dp *Pool, runtime bool) {
 	if runtime {
 		do.Runtime.Set(pkg.Name())
@@ -125,6 +147,60 @@ func (do *Order) GetMake() []string {
 	return makeOnly
 }
 
+// Requirement is a package that requires another one through an edge type
+type Requirement struct {
+	Name string
+	Type string
+}
+
+// RequiredBy returns the packages of the order that depend on name
+func (do *Order) RequiredBy(name string) []Requirement {
+	satisfies := func(string) bool { return false }
+	for _, base := range do.Aur {
+		for _, pkg := range base {
+			if pkg.Name == name {
+				aurPkg := pkg
+				satisfies = func(dep string) bool { return satisfiesAur(dep, aurPkg) }
+			}
+		}
+	}
+	for _, pkg := range do.Repo {
+		if pkg.Name() == name {
+			repoPkg := pkg
+			satisfies = func(dep string) bool { return satisfiesRepo(dep, repoPkg) }
+		}
+	}
+
+	requiredBy := make([]Requirement, 0)
+	for _, base := range do.Aur {
+		for _, pkg := range base {
+			for i, deps := range [3][]string{pkg.Depends, pkg.MakeDepends, pkg.CheckDepends} {
+				edgeType := [3]string{EdgeDepends, EdgeMakeDepends, EdgeCheckDepends}[i]
+				for _, dep := range deps {
+					if satisfies(dep) {
+						requiredBy = append(requiredBy, Requirement{pkg.Name, edgeType})
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for _, pkg := range do.Repo {
+		required := false
+		_ = pkg.Depends().ForEach(func(dep alpm.Depend) error {
+			required = required || satisfies(dep.String())
+			return nil
+		})
+
+		if required {
+			requiredBy = append(requiredBy, Requirement{pkg.Name(), EdgeDepends})
+		}
+	}
+
+	return requiredBy
+}
+
 // Print prints repository packages to be downloaded
 func (do *Order) Print() {
 	repo := ""
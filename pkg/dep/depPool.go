@@ -60,6 +60,16 @@ type Pool struct {
 	LocalDB  *alpm.DB
 	SyncDB   alpm.DBList
 	Warnings *query.AURWarnings
+
+	// ProviderRules choose between the providers of AUR dependencies
+	ProviderRules []settings.ProviderRule
+
+	// targets that must not be satisfied by a repo provider
+	aurOnly stringset.StringSet
+
+	// dbs answers the lookups resolution makes in the local and sync
+	// databases
+	dbs repoDBs
 }
 
 func makePool(alpmHandle *alpm.Handle) (*Pool, error) {
@@ -82,6 +92,9 @@ func makePool(alpmHandle *alpm.Handle) (*Pool, error) {
 		localDB,
 		syncDB,
 		nil,
+		nil,
+		make(stringset.StringSet),
+		&alpmDBs{local: localDB, sync: syncDB},
 	}
 
 	return dp, nil
@@ -118,6 +131,7 @@ func (dp *Pool) ResolveTargets(pkgs []string, alpmHandle *alpm.Handle,
 		if target.DB == "aur" || mode == settings.ModeAUR {
 			dp.Targets = append(dp.Targets, target)
 			aurTargets.Set(target.DepString())
+			dp.aurOnly.Set(target.DepString())
 			continue
 		}
 
@@ -130,7 +144,20 @@ func (dp *Pool) ResolveTargets(pkgs []string, alpmHandle *alpm.Handle,
 			foundPkg, err = singleDB.PkgCache().FindSatisfier(target.DepString())
 			// otherwise find it in any repo
 		} else {
-			foundPkg, err = dp.SyncDB.FindSatisfier(target.DepString())
+			// a target that is only provided by repo packages may be
+			// provided by AUR packages as well, the provider menu offers
+			// both instead of pacman's
+			unified := provides && mode == settings.ModeAny
+			hm := settings.HideMenus
+			settings.HideMenus = unified
+			foundPkg, err = dp.dbs.syncSatisfier(target.DepString())
+			settings.HideMenus = hm
+
+			if err == nil && unified && foundPkg.Name() != target.Name {
+				dp.Targets = append(dp.Targets, target)
+				aurTargets.Set(target.DepString())
+				continue
+			}
 		}
 
 		if err == nil {
@@ -199,7 +226,7 @@ func (dp *Pool) findProvides(pkgs stringset.StringSet) error {
 		words := strings.Split(pkg, "-")
 
 		for i := range words {
-			results, err = rpc.Search(strings.Join(words[:i+1], "-"))
+			results, err = query.Client.Search(strings.Join(words[:i+1], "-"), rpc.NameDesc)
 			if err == nil {
 				break
 			}
@@ -219,7 +246,7 @@ func (dp *Pool) findProvides(pkgs stringset.StringSet) error {
 	}
 
 	for pkg := range pkgs {
-		if dp.LocalDB.Pkg(pkg) != nil {
+		if dp.dbs.installed(pkg) {
 			continue
 		}
 		wg.Add(1)
@@ -297,7 +324,15 @@ func (dp *Pool) resolveAURPackages(pkgs stringset.StringSet,
 			continue
 		}
 
-		pkg := dp.findSatisfierAurCache(name, ignoreProviders, noConfirm, provides)
+		pkg, repoPkg := dp.findSatisfierAurCache(name, ignoreProviders, noConfirm, provides)
+		if repoPkg != nil {
+			if explicit {
+				dp.Explicit.Set(repoPkg.Name())
+			}
+			dp.ResolveRepoDependency(repoPkg)
+			continue
+		}
+
 		if pkg == nil {
 			continue
 		}
@@ -319,12 +354,19 @@ func (dp *Pool) resolveAURPackages(pkgs stringset.StringSet,
 			continue
 		}
 
-		_, isInstalled := dp.LocalDB.PkgCache().FindSatisfier(dep) // has satisfier installed: skip
+		isInstalled := dp.dbs.installedSatisfies(dep) // has satisfier installed: skip
 		hm := settings.HideMenus
-		settings.HideMenus = isInstalled == nil
-		repoPkg, inRepos := dp.SyncDB.FindSatisfier(dep) // has satisfier in repo: fetch it
+		settings.HideMenus = isInstalled || provides
+		repoPkg, inRepos := dp.dbs.syncSatisfier(dep) // has satisfier in repo: fetch it
 		settings.HideMenus = hm
-		if isInstalled == nil && (rebuild != "tree" || inRepos == nil) {
+		if isInstalled && (rebuild != "tree" || inRepos == nil) {
+			continue
+		}
+
+		// a dependency that repo packages only provide may be provided by
+		// AUR packages as well, the provider menu offers both
+		if inRepos == nil && provides && !dp.repoHasPackage(dep) {
+			newAURPackages.Set(dep)
 			continue
 		}
 
@@ -352,13 +394,12 @@ func (dp *Pool) ResolveRepoDependency(pkg *alpm.Package) {
 		}
 
 		// has satisfier installed: skip
-		_, isInstalled := dp.LocalDB.PkgCache().FindSatisfier(dep.String())
-		if isInstalled == nil {
+		if dp.dbs.installedSatisfies(dep.String()) {
 			return
 		}
 
 		// has satisfier in repo: fetch it
-		repoPkg, inRepos := dp.SyncDB.FindSatisfier(dep.String())
+		repoPkg, inRepos := dp.dbs.syncSatisfier(dep.String())
 		if inRepos != nil {
 			return
 		}
@@ -374,13 +415,14 @@ func GetPool(pkgs []string,
 	alpmHandle *alpm.Handle,
 	mode settings.TargetMode,
 	ignoreProviders, noConfirm, provides bool,
-	rebuild string, splitN int) (*Pool, error) {
+	rebuild string, splitN int, providerRules []settings.ProviderRule) (*Pool, error) {
 	dp, err := makePool(alpmHandle)
 	if err != nil {
 		return nil, err
 	}
 
 	dp.Warnings = warnings
+	dp.ProviderRules = providerRules
 	err = dp.ResolveTargets(pkgs, alpmHandle, mode, ignoreProviders, noConfirm, provides, rebuild, splitN)
 
 	return dp, err
@@ -405,15 +447,16 @@ func (dp *Pool) findSatisfierAur(dep string) *rpc.Pkg {
 // foo and foo-git.
 // Using Pacman's ways trying to install foo would never give you
 // a menu.
-// TODO: maybe intermix repo providers in the menu
-func (dp *Pool) findSatisfierAurCache(dep string, ignoreProviders, noConfirm, provides bool) *rpc.Pkg {
+// Repo providers are offered in the same menu, ahead of the AUR ones, and
+// are returned as the second value when chosen.
+func (dp *Pool) findSatisfierAurCache(dep string, ignoreProviders, noConfirm, provides bool) (*rpc.Pkg, *alpm.Package) {
 	depName, _, _ := splitDep(dep)
 	seen := make(stringset.StringSet)
 	providerSlice := makeProviders(depName)
 
-	if dp.LocalDB.Pkg(depName) != nil {
+	if dp.dbs.installed(depName) {
 		if pkg, ok := dp.AurCache[dep]; ok && pkgSatisfies(pkg.Name, pkg.Version, dep) {
-			return pkg
+			return pkg, nil
 		}
 	}
 
@@ -422,7 +465,7 @@ func (dp *Pool) findSatisfierAurCache(dep string, ignoreProviders, noConfirm, pr
 			if pkgSatisfies(pkg.Name, pkg.Version, dep) {
 				for _, target := range dp.Targets {
 					if target.Name == pkg.Name {
-						return pkg
+						return pkg, nil
 					}
 				}
 			}
@@ -449,20 +492,87 @@ func (dp *Pool) findSatisfierAurCache(dep string, ignoreProviders, noConfirm, pr
 		}
 	}
 
-	if !provides && providerSlice.Len() >= 1 {
-		return providerSlice.Pkgs[0]
+	repoProviders := make([]repoProvider, 0)
+	if !dp.aurOnly.Get(dep) {
+		repoProviders = dp.repoProviders(dep)
 	}
 
-	if providerSlice.Len() == 1 {
-		return providerSlice.Pkgs[0]
+	if len(repoProviders) == 0 {
+		if !provides && providerSlice.Len() >= 1 {
+			return providerSlice.Pkgs[0], nil
+		}
+
+		if providerSlice.Len() == 1 {
+			return providerSlice.Pkgs[0], nil
+		}
+	} else if providerSlice.Len() == 0 && len(repoProviders) == 1 {
+		return nil, repoProviders[0].pkg
 	}
 
-	if providerSlice.Len() > 1 {
-		sort.Sort(providerSlice)
-		return providerMenu(dep, providerSlice, noConfirm)
+	if providerSlice.Len()+len(repoProviders) == 0 {
+		return nil, nil
 	}
 
-	return nil
+	sort.Sort(providerSlice)
+	choices := make([]providerChoice, 0, providerSlice.Len()+len(repoProviders))
+	for i := range repoProviders {
+		choices = append(choices, providerChoice{repo: &repoProviders[i]})
+	}
+	for _, pkg := range providerSlice.Pkgs {
+		choices = append(choices, providerChoice{aur: pkg})
+	}
+
+	// a target named by the user is never replaced by a rule
+	names := make([]string, 0, len(choices))
+	exact := false
+	for _, choice := range choices {
+		names = append(names, choice.name())
+		exact = exact || choice.name() == depName
+	}
+
+	if !exact || !dp.isTarget(depName) {
+		kept, chosen := ChooseProvider(dp.ProviderRules, dep, names)
+		if chosen >= 0 {
+			return choices[chosen].aur, choices[chosen].repoPkg()
+		}
+
+		keptChoices := make([]providerChoice, 0, len(kept))
+		for _, i := range kept {
+			keptChoices = append(keptChoices, choices[i])
+		}
+		choices = keptChoices
+	}
+
+	choice := dp.providerMenu(dep, choices, noConfirm)
+	return choice.aur, choice.repoPkg()
+}
+
+// repoProviders returns every package of the sync databases that satisfies
+// dep, in database order
+func (dp *Pool) repoProviders(dep string) []repoProvider {
+	// the providers are only indexed once a repo package satisfies a
+	// dependency
+	hm := settings.HideMenus
+	settings.HideMenus = true
+	_, err := dp.dbs.syncSatisfier(dep)
+	settings.HideMenus = hm
+	if err != nil {
+		return []repoProvider{}
+	}
+
+	return dp.dbs.syncProviders(dep)
+}
+
+// repoHasPackage returns whether a sync package named like dep satisfies it
+func (dp *Pool) repoHasPackage(dep string) bool {
+	depName, _, _ := splitDep(dep)
+	for _, provider := range dp.dbs.syncProviders(dep) {
+		if provider.name == depName {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (dp *Pool) findSatisfierRepo(dep string) *alpm.Package {
@@ -479,6 +589,16 @@ func (dp *Pool) hasSatisfier(dep string) bool {
 	return dp.findSatisfierRepo(dep) != nil || dp.findSatisfierAur(dep) != nil
 }
 
+func (dp *Pool) isTarget(name string) bool {
+	for _, target := range dp.Targets {
+		if target.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (dp *Pool) hasPackage(name string) bool {
 	for _, pkg := range dp.Repo {
 		if pkg.Name() == name {
@@ -501,27 +621,75 @@ func (dp *Pool) hasPackage(name string) bool {
 	return false
 }
 
-func providerMenu(dep string, providers providers, noConfirm bool) *rpc.Pkg {
-	size := providers.Len()
+// providerChoice is a candidate of the provider menu, either a repo or an
+// AUR package
+type providerChoice struct {
+	repo *repoProvider
+	aur  *rpc.Pkg
+}
+
+func (c providerChoice) name() string {
+	if c.repo != nil {
+		return c.repo.name
+	}
+
+	return c.aur.Name
+}
+
+func (c providerChoice) db() string {
+	if c.repo != nil {
+		return c.repo.db
+	}
+
+	return "AUR"
+}
+
+func (c providerChoice) version() string {
+	if c.repo != nil {
+		return c.repo.version
+	}
+
+	return c.aur.Version
+}
+
+func (c providerChoice) repoPkg() *alpm.Package {
+	if c.repo != nil {
+		return c.repo.pkg
+	}
+
+	return nil
+}
+
+func (dp *Pool) providerMenu(dep string, choices []providerChoice, noConfirm bool) providerChoice {
+	size := len(choices)
 
 	str := text.Bold(gotext.Get("There are %d providers available for %s:\n", size, dep))
 
-	size = 1
-	str += text.SprintOperationInfo(gotext.Get("Repository AUR"), "\n    ")
+	var db string
+	for i, choice := range choices {
+		if db != choice.db() {
+			db = choice.db()
+			str += text.SprintOperationInfo(gotext.Get("Repository")+" "+db, "\n")
+		}
 
-	for _, pkg := range providers.Pkgs {
-		str += fmt.Sprintf("%d) %s ", size, pkg.Name)
-		size++
+		str += fmt.Sprintf("    %d) %s %s", i+1, text.Bold(choice.name()), text.Cyan(choice.version()))
+		if choice.aur != nil {
+			str += text.Bold(fmt.Sprintf(" (+%d)", choice.aur.NumVotes))
+		}
+		if dp.dbs.installed(choice.name()) {
+			str += text.Bold(" " + gotext.Get("(Installed)"))
+		}
+		str += "\n"
 	}
 
-	text.OperationInfoln(str)
+	text.OperationInfo(str)
 
 	for {
-		fmt.Print(gotext.Get("\nEnter a number (default=1): "))
+		fmt.Fprint(text.Out(), gotext.Get("\nEnter a number (default=1): "))
 
 		if noConfirm {
-			fmt.Println("1")
-			return providers.Pkgs[0]
+			fmt.Fprintln(text.Out(), "1")
+			return choices[0]
 		}
 
 		reader := bufio.NewReader(os.Stdin)
@@ -537,7 +705,7 @@ func providerMenu(dep string, providers providers, noConfirm bool) *rpc.Pkg {
 		}
 
 		if string(numberBuf) == "" {
-			return providers.Pkgs[0]
+			return choices[0]
 		}
 
 		num, err := strconv.Atoi(string(numberBuf))
@@ -546,13 +714,13 @@ func providerMenu(dep string, providers providers, noConfirm bool) *rpc.Pkg {
 			continue
 		}
 
-		if num < 1 || num >= size {
-			text.Errorln(gotext.Get("invalid value: %d is not between %d and %d", num, 1, size-1))
+		if num < 1 || num > size {
+			text.Errorln(gotext.Get("invalid value: %d is not between %d and %d", num, 1, size))
 			continue
 		}
 
-		return providers.Pkgs[num-1]
+		return choices[num-1]
 	}
 
-	return nil
+	return providerChoice{}
 }
@@ -0,0 +1,217 @@
+package dep
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	alpm "github.com/Jguer/go-alpm"
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// fakeDBs has installed packages and sync packages that only carry what the
+// provider menu shows
+type fakeDBs struct {
+	installedPkgs stringset.StringSet
+	sync          map[string][]repoProvider
+}
+
+func (dbs fakeDBs) installed(name string) bool {
+	return dbs.installedPkgs.Get(name)
+}
+
+func (dbs fakeDBs) installedSatisfies(dep string) bool {
+	name, _, _ := splitDep(dep)
+	return dbs.installedPkgs.Get(name)
+}
+
+func (dbs fakeDBs) syncSatisfier(dep string) (*alpm.Package, error) {
+	if providers := dbs.syncProviders(dep); len(providers) > 0 {
+		return providers[0].pkg, nil
+	}
+
+	return nil, errors.New("unable to satisfy dependency " + dep)
+}
+
+func (dbs fakeDBs) syncProviders(dep string) []repoProvider {
+	name, _, _ := splitDep(dep)
+	return append([]repoProvider{}, dbs.sync[name]...)
+}
+
+func newTestPool(installed ...string) *Pool {
+	return &Pool{
+		Targets:  make([]Target, 0),
+		Explicit: make(stringset.StringSet),
+		Repo:     make(map[string]*alpm.Package),
+		Aur:      make(map[string]*rpc.Pkg),
+		AurCache: make(map[string]*rpc.Pkg),
+		Warnings: &query.AURWarnings{},
+		aurOnly:  make(stringset.StringSet),
+		dbs:      fakeDBs{stringset.FromSlice(installed), make(map[string][]repoProvider)},
+	}
+}
+
+// captureOutput sends the messages of the text package to a buffer until
+// the returned function is called
+func captureOutput() (*bytes.Buffer, func()) {
+	var buf bytes.Buffer
+	text.SetOutput(&buf)
+	return &buf, func() { text.SetOutput(nil) }
+}
+
+func TestFindSatisfierAurCache(t *testing.T) {
+	foo := &rpc.Pkg{Name: "foo", Version: "1.0-1"}
+	fooGit := &rpc.Pkg{Name: "foo-git", Version: "r10-1", Provides: []string{"foo"}}
+	fooBin := &rpc.Pkg{Name: "foo-bin", Version: "1.0-1", Provides: []string{"foo"}}
+	bar := &rpc.Pkg{Name: "bar", Version: "2.0-1"}
+	fooRepo := repoProvider{&alpm.Package{}, "foo-repo", "1.0-1", "extra"}
+
+	testCases := []struct {
+		name            string
+		installed       []string
+		targets         []string
+		rules           []settings.ProviderRule
+		ignoreProviders bool
+		repo            []repoProvider
+		dep             string
+		want            *rpc.Pkg
+		wantRepo        *alpm.Package
+		menu            bool
+	}{
+		{name: "single provider", dep: "bar", want: bar},
+		{name: "menu lists the exact name first", dep: "foo", want: foo, menu: true},
+		{name: "preferred by a rule", dep: "foo", want: fooBin,
+			rules: []settings.ProviderRule{{Dep: "foo", Prefer: []string{"*-bin"}}}},
+		{name: "rules skip the menu for the last provider left", dep: "foo", want: fooGit,
+			rules: []settings.ProviderRule{{Dep: "*", Avoid: []string{"foo", "*-bin"}}}},
+		{name: "target skips the menu with ignoreproviders", dep: "foo", want: foo,
+			targets: []string{"foo"}, ignoreProviders: true},
+		{name: "installed package is kept", dep: "foo", want: foo, installed: []string{"foo"}},
+		{name: "no provider", dep: "baz"},
+		{name: "single repo provider", dep: "baz", repo: []repoProvider{fooRepo}, wantRepo: fooRepo.pkg},
+		{name: "menu lists repo providers first", dep: "foo", repo: []repoProvider{fooRepo},
+			wantRepo: fooRepo.pkg, menu: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, restore := captureOutput()
+			defer restore()
+
+			dp := newTestPool(tc.installed...)
+			dp.ProviderRules = tc.rules
+			dp.dbs.(fakeDBs).sync[tc.dep] = tc.repo
+			for _, pkg := range []*rpc.Pkg{foo, fooGit, fooBin, bar} {
+				dp.AurCache[pkg.Name] = pkg
+			}
+			for _, target := range tc.targets {
+				dp.Targets = append(dp.Targets, ToTarget(target))
+			}
+
+			pkg, repoPkg := dp.findSatisfierAurCache(tc.dep, tc.ignoreProviders, true, true)
+			assert.Equal(t, tc.want, pkg)
+			assert.Equal(t, tc.wantRepo, repoPkg)
+			assert.Equal(t, tc.menu, buf.Len() > 0)
+		})
+	}
+}
+
+func TestProviderMenu(t *testing.T) {
+	useColor := text.UseColor
+	defer func() { text.UseColor = useColor }()
+	text.UseColor = false
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	choices := []providerChoice{
+		{repo: &repoProvider{&alpm.Package{}, "foo-repo", "1.1-1", "extra"}},
+		{aur: &rpc.Pkg{Name: "foo", Version: "1.0-1", NumVotes: 12}},
+		{aur: &rpc.Pkg{Name: "foo-git", Version: "r10-1", NumVotes: 3}},
+	}
+
+	dp := newTestPool("foo-git")
+	choice := dp.providerMenu("foo", choices, true)
+	assert.Equal(t, choices[0], choice)
+
+	assert.Contains(t, buf.String(), "There are 3 providers available for foo:")
+	assert.Contains(t, buf.String(), "Repository extra")
+	assert.Contains(t, buf.String(), "1) foo-repo 1.1-1\n")
+	assert.Contains(t, buf.String(), "Repository AUR")
+	assert.Contains(t, buf.String(), "2) foo 1.0-1 (+12)\n")
+	assert.Contains(t, buf.String(), "3) foo-git r10-1 (+3) (Installed)\n")
+}
+
+func TestProviderChoice(t *testing.T) {
+	choice := providerChoice{aur: &rpc.Pkg{Name: "foo-git", Version: "r10-1"}}
+	assert.Equal(t, "foo-git", choice.name())
+	assert.Equal(t, "AUR", choice.db())
+	assert.Equal(t, "r10-1", choice.version())
+	assert.Nil(t, choice.repoPkg())
+
+	repo := &repoProvider{&alpm.Package{}, "foo", "1.0-1", "extra"}
+	choice = providerChoice{repo: repo}
+	assert.Equal(t, "foo", choice.name())
+	assert.Equal(t, "extra", choice.db())
+	assert.Equal(t, "1.0-1", choice.version())
+	assert.Equal(t, repo.pkg, choice.repoPkg())
+}
+
+func TestResolveAURPackagesMemoryClient(t *testing.T) {
+	defer func(client query.AURClient) { query.Client = client }(query.Client)
+	query.Client = &query.MemoryClient{Pkgs: []rpc.Pkg{
+		{Name: "app", Version: "1.0-1", Maintainer: "someone",
+			Depends:     []string{"libapp", "virtual-dep", "missing-dep", "installed-dep"},
+			MakeDepends: []string{"app-builder"}},
+		{Name: "libapp", Version: "1.0-1", Maintainer: "someone"},
+		{Name: "virtual-provider", Version: "1.0-1", Maintainer: "someone", Provides: []string{"virtual-dep"}},
+		{Name: "app-builder", Version: "1.0-1", Maintainer: "someone"},
+		{Name: "installed-dep", Version: "1.0-1", Maintainer: "someone"},
+	}}
+
+	_, restore := captureOutput()
+	defer restore()
+
+	dp := newTestPool("installed-dep")
+	err := dp.resolveAURPackages(stringset.FromSlice([]string{"app"}), true, false, true, true, "no", 150)
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(dp.Aur))
+	for name := range dp.Aur {
+		names = append(names, name)
+	}
+
+	assert.ElementsMatch(t, []string{"app", "libapp", "virtual-provider", "app-builder"}, names)
+	assert.Equal(t, stringset.FromSlice([]string{"app"}), dp.Explicit)
+	assert.Contains(t, dp.Warnings.Missing, "missing-dep")
+	assert.True(t, dp.hasSatisfier("virtual-dep"))
+	assert.False(t, dp.hasSatisfier("missing-dep"))
+}
+
+func TestResolveAURPackagesRepoAndAURProviders(t *testing.T) {
+	defer func(client query.AURClient) { query.Client = client }(query.Client)
+	query.Client = &query.MemoryClient{Pkgs: []rpc.Pkg{
+		{Name: "app", Version: "1.0-1", Maintainer: "someone", Depends: []string{"java-runtime"}},
+		{Name: "jdk-bin", Version: "17-1", Maintainer: "someone", Description: "Java runtime", Provides: []string{"java-runtime"}},
+	}}
+
+	buf, restore := captureOutput()
+	defer restore()
+
+	dp := newTestPool()
+	dp.dbs.(fakeDBs).sync["java-runtime"] = []repoProvider{{&alpm.Package{}, "jre-openjdk", "17-1", "extra"}}
+	dp.ProviderRules = []settings.ProviderRule{{Dep: "java-runtime", Prefer: []string{"jdk-bin"}}}
+
+	err := dp.resolveAURPackages(stringset.FromSlice([]string{"app"}), true, false, true, true, "no", 150)
+	assert.NoError(t, err)
+
+	assert.Contains(t, dp.Aur, "jdk-bin")
+	assert.Empty(t, dp.Repo)
+	assert.Empty(t, buf.String(), "the rule picks the AUR provider without a menu")
+}
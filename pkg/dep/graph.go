@@ -0,0 +1,232 @@
+package dep
+
+import (
+	alpm "github.com/Jguer/go-alpm"
+	rpc "github.com/mikkeloscar/aur"
+)
+
+// Types of graph edges
+const (
+	EdgeDepends      = "depends"
+	EdgeMakeDepends  = "makedepends"
+	EdgeCheckDepends = "checkdepends"
+	EdgeProvides     = "provides"
+)
+
+// GraphNode is a package of a dependency graph. Source is aur, the name of
+// the sync database or local for installed packages that aren't part of the
+// transaction. Virtual nodes are dependencies that are satisfied through
+// provides.
+type GraphNode struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Target    bool   `json:"target,omitempty"`
+	Installed bool   `json:"installed,omitempty"`
+	MakeOnly  bool   `json:"makeonly,omitempty"`
+	CheckOnly bool   `json:"checkonly,omitempty"`
+	Virtual   bool   `json:"virtual,omitempty"`
+}
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// Graph is the dependency graph of the targets of a Pool
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []GraphEdge  `json:"edges"`
+
+	index map[string]*GraphNode
+}
+
+func (g *Graph) node(name string) (*GraphNode, bool) {
+	if node, ok := g.index[name]; ok {
+		return node, false
+	}
+
+	node := &GraphNode{Name: name}
+	g.index[name] = node
+	g.Nodes = append(g.Nodes, node)
+
+	return node, true
+}
+
+func (g *Graph) addEdge(from, to, edgeType string) {
+	for _, edge := range g.Edges {
+		if edge.From == from && edge.To == to && edge.Type == edgeType {
+			return
+		}
+	}
+
+	g.Edges = append(g.Edges, GraphEdge{from, to, edgeType})
+}
+
+// Graph returns the dependency graph of the resolved targets. It has to be
+// called before GetOrder, which consumes the pool. Installed packages that
+// satisfy a dependency are leaves of the graph.
+func (dp *Pool) Graph() *Graph {
+	g := &Graph{
+		Nodes: make([]*GraphNode, 0),
+		Edges: make([]GraphEdge, 0),
+		index: make(map[string]*GraphNode),
+	}
+
+	var addAur func(pkg *rpc.Pkg) string
+	var addRepo func(pkg *alpm.Package) string
+
+	// resolve adds the satisfier of dep and links it to from
+	resolve := func(from, dep, edgeType string) {
+		depName, _, _ := splitDep(dep)
+		to := ""
+
+		if aurPkg := dp.findSatisfierAur(dep); aurPkg != nil {
+			to = addAur(aurPkg)
+		} else if repoPkg := dp.findSatisfierRepo(dep); repoPkg != nil {
+			to = addRepo(repoPkg)
+		} else if localPkg, err := dp.LocalDB.PkgCache().FindSatisfier(dep); err == nil {
+			node, created := g.node(localPkg.Name())
+			if created {
+				node.Version = localPkg.Version()
+				node.Source = "local"
+				node.Installed = true
+			}
+			to = node.Name
+		} else {
+			return
+		}
+
+		if to != depName {
+			if node, created := g.node(depName); created {
+				node.Virtual = true
+			}
+			g.addEdge(from, depName, edgeType)
+			g.addEdge(to, depName, EdgeProvides)
+			return
+		}
+
+		g.addEdge(from, to, edgeType)
+	}
+
+	addAur = func(pkg *rpc.Pkg) string {
+		node, created := g.node(pkg.Name)
+		if !created {
+			return pkg.Name
+		}
+
+		node.Version = pkg.Version
+		node.Source = "aur"
+		node.Installed = dp.LocalDB.Pkg(pkg.Name) != nil
+
+		for i, deps := range [3][]string{pkg.Depends, pkg.MakeDepends, pkg.CheckDepends} {
+			edgeType := [3]string{EdgeDepends, EdgeMakeDepends, EdgeCheckDepends}[i]
+			for _, dep := range deps {
+				resolve(pkg.Name, dep, edgeType)
+			}
+		}
+
+		return pkg.Name
+	}
+
+	addRepo = func(pkg *alpm.Package) string {
+		node, created := g.node(pkg.Name())
+		if !created {
+			return pkg.Name()
+		}
+
+		node.Version = pkg.Version()
+		node.Source = pkg.DB().Name()
+		node.Installed = dp.LocalDB.Pkg(pkg.Name()) != nil
+
+		_ = pkg.Depends().ForEach(func(dep alpm.Depend) error {
+			resolve(pkg.Name(), dep.String(), EdgeDepends)
+			return nil
+		})
+
+		return pkg.Name()
+	}
+
+	for _, target := range dp.Targets {
+		dep := target.DepString()
+		name := ""
+
+		if aurPkg := dp.findSatisfierAur(dep); aurPkg != nil {
+			name = addAur(aurPkg)
+		} else if repoPkg := dp.findSatisfierRepo(dep); repoPkg != nil {
+			name = addRepo(repoPkg)
+		}
+
+		if name != "" {
+			g.index[name].Target = true
+		}
+	}
+
+	g.markBuildOnly()
+	return g
+}
+
+// markBuildOnly marks the nodes that are only needed to build or to check
+// the targets
+func (g *Graph) markBuildOnly() {
+	// follow returns the nodes reachable from start through depends edges.
+	// A virtual node leads to its providers.
+	follow := func(start []string) map[string]bool {
+		seen := make(map[string]bool)
+		queue := start
+
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			for _, edge := range g.Edges {
+				switch {
+				case edge.Type == EdgeDepends && edge.From == name:
+					queue = append(queue, edge.To)
+				case edge.Type == EdgeProvides && edge.To == name:
+					queue = append(queue, edge.From)
+				}
+			}
+		}
+
+		return seen
+	}
+
+	targets := make([]string, 0)
+	for _, node := range g.Nodes {
+		if node.Target {
+			targets = append(targets, node.Name)
+		}
+	}
+
+	runtime := follow(targets)
+
+	// the direct build dependencies of the packages being built and
+	// everything they need at runtime
+	direct := func(edgeType string) []string {
+		names := make([]string, 0)
+		for _, edge := range g.Edges {
+			if edge.Type == edgeType {
+				names = append(names, edge.To)
+			}
+		}
+		return names
+	}
+
+	makeDeps := follow(direct(EdgeMakeDepends))
+	checkDeps := follow(direct(EdgeCheckDepends))
+
+	for _, node := range g.Nodes {
+		if runtime[node.Name] {
+			continue
+		}
+
+		node.MakeOnly = makeDeps[node.Name]
+		node.CheckOnly = !node.MakeOnly && checkDeps[node.Name]
+	}
+}
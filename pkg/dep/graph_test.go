@@ -0,0 +1,42 @@
+package dep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphMarkBuildOnly(t *testing.T) {
+	g := &Graph{index: make(map[string]*GraphNode)}
+	for _, name := range []string{"yay", "pacman", "go", "sh", "bash", "python-pytest", "python"} {
+		g.node(name)
+	}
+	g.index["yay"].Target = true
+	g.index["sh"].Virtual = true
+
+	g.addEdge("yay", "pacman", EdgeDepends)
+	g.addEdge("yay", "go", EdgeMakeDepends)
+	g.addEdge("yay", "python-pytest", EdgeCheckDepends)
+	g.addEdge("go", "sh", EdgeDepends)
+	g.addEdge("bash", "sh", EdgeProvides)
+	g.addEdge("python-pytest", "python", EdgeDepends)
+	g.addEdge("python-pytest", "python", EdgeDepends)
+	g.addEdge("pacman", "python", EdgeDepends)
+
+	g.markBuildOnly()
+
+	makeOnly := make([]string, 0)
+	checkOnly := make([]string, 0)
+	for _, node := range g.Nodes {
+		if node.MakeOnly {
+			makeOnly = append(makeOnly, node.Name)
+		}
+		if node.CheckOnly {
+			checkOnly = append(checkOnly, node.Name)
+		}
+	}
+
+	assert.Equal(t, []string{"go", "sh", "bash"}, makeOnly)
+	assert.Equal(t, []string{"python-pytest"}, checkOnly)
+	assert.Len(t, g.Edges, 7)
+}
@@ -0,0 +1,66 @@
+package dep
+
+import (
+	"path"
+
+	"github.com/Jguer/yay/v10/pkg/settings"
+)
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ChooseProvider applies the provider rules matching dep to the names of its
+// providers. It returns the indexes of the providers that aren't avoided and
+// the index of the provider to pick, -1 when the user has to be asked.
+// A rule that would avoid every provider left is ignored.
+func ChooseProvider(rules []settings.ProviderRule, dep string, providers []string) (kept []int, chosen int) {
+	depName, _, _ := splitDep(dep)
+
+	matching := make([]settings.ProviderRule, 0)
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Dep, depName); ok {
+			matching = append(matching, rule)
+		}
+	}
+
+	kept = make([]int, 0, len(providers))
+	for i := range providers {
+		kept = append(kept, i)
+	}
+
+	for _, rule := range matching {
+		allowed := make([]int, 0, len(kept))
+		for _, i := range kept {
+			if !matchesAny(rule.Avoid, providers[i]) {
+				allowed = append(allowed, i)
+			}
+		}
+
+		if len(allowed) > 0 {
+			kept = allowed
+		}
+	}
+
+	for _, rule := range matching {
+		for _, pattern := range rule.Prefer {
+			for _, i := range kept {
+				if ok, _ := path.Match(pattern, providers[i]); ok {
+					return kept, i
+				}
+			}
+		}
+	}
+
+	if len(kept) == 1 {
+		return kept, kept[0]
+	}
+
+	return kept, -1
+}
@@ -0,0 +1,43 @@
+package dep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/settings"
+)
+
+func TestChooseProvider(t *testing.T) {
+	rules := []settings.ProviderRule{
+		{Dep: "java-runtime", Prefer: []string{"jdk17-openjdk"}},
+		{Dep: "*", Prefer: []string{"*-bin"}, Avoid: []string{"*-git"}},
+	}
+
+	testCases := []struct {
+		name      string
+		dep       string
+		providers []string
+		kept      []int
+		chosen    int
+	}{
+		{"per dependency rule first", "java-runtime>=11",
+			[]string{"jdk11-openjdk", "jdk17-openjdk-bin", "jdk17-openjdk"}, []int{0, 1, 2}, 2},
+		{"glob preference", "foo", []string{"foo", "foo-bin", "foo-git"}, []int{0, 1}, 1},
+		{"avoided providers are dropped", "foo", []string{"foo", "foo-git", "foo-hg"}, []int{0, 2}, -1},
+		{"single provider left", "foo", []string{"foo", "foo-git"}, []int{0}, 0},
+		{"never avoid everything", "foo", []string{"foo-git", "bar-git"}, []int{0, 1}, -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kept, chosen := ChooseProvider(rules, tc.dep, tc.providers)
+			assert.Equal(t, tc.kept, kept)
+			assert.Equal(t, tc.chosen, chosen)
+		})
+	}
+
+	kept, chosen := ChooseProvider(nil, "foo", []string{"foo", "foo-git"})
+	assert.Equal(t, []int{0, 1}, kept)
+	assert.Equal(t, -1, chosen)
+}
@@ -0,0 +1,88 @@
+package dep
+
+import (
+	alpm "github.com/Jguer/go-alpm"
+)
+
+// repoDBs are the lookups dependency resolution makes in the local and sync
+// databases
+type repoDBs interface {
+	// installed returns whether a package is installed
+	installed(name string) bool
+	// installedSatisfies returns whether an installed package satisfies dep
+	installedSatisfies(dep string) bool
+	// syncSatisfier returns the sync package alpm picks for dep
+	syncSatisfier(dep string) (*alpm.Package, error)
+	// syncProviders returns every sync package that satisfies dep, in
+	// database order
+	syncProviders(dep string) []repoProvider
+}
+
+// repoProvider is a sync package that satisfies a dependency along with what
+// the provider menu shows of it
+type repoProvider struct {
+	pkg     *alpm.Package
+	name    string
+	version string
+	db      string
+}
+
+// alpmDBs looks packages up in the databases of an alpm handle
+type alpmDBs struct {
+	local *alpm.DB
+	sync  alpm.DBList
+
+	// providers indexes the sync packages by their name and the names
+	// they provide. It is built on first use.
+	providers map[string][]*alpm.Package
+}
+
+func (dbs *alpmDBs) installed(name string) bool {
+	return dbs.local.Pkg(name) != nil
+}
+
+func (dbs *alpmDBs) installedSatisfies(dep string) bool {
+	_, err := dbs.local.PkgCache().FindSatisfier(dep)
+	return err == nil
+}
+
+func (dbs *alpmDBs) syncSatisfier(dep string) (*alpm.Package, error) {
+	return dbs.sync.FindSatisfier(dep)
+}
+
+func (dbs *alpmDBs) syncProviders(dep string) []repoProvider {
+	if dbs.providers == nil {
+		dbs.indexProviders()
+	}
+
+	depName, _, _ := splitDep(dep)
+	providers := make([]repoProvider, 0)
+	for _, pkg := range dbs.providers[depName] {
+		if satisfiesRepo(dep, pkg) {
+			providers = append(providers, repoProvider{pkg, pkg.Name(), pkg.Version(), pkg.DB().Name()})
+		}
+	}
+
+	return providers
+}
+
+func (dbs *alpmDBs) indexProviders() {
+	dbs.providers = make(map[string][]*alpm.Package)
+
+	add := func(name string, pkg *alpm.Package) {
+		list := dbs.providers[name]
+		if len(list) == 0 || list[len(list)-1] != pkg {
+			dbs.providers[name] = append(list, pkg)
+		}
+	}
+
+	_ = dbs.sync.ForEach(func(db alpm.DB) error {
+		return db.PkgCache().ForEach(func(pkg alpm.Package) error {
+			add(pkg.Name(), &pkg)
+			return pkg.Provides().ForEach(func(provide alpm.Depend) error {
+				add(provide.Name, &pkg)
+				return nil
+			})
+		})
+	})
+}
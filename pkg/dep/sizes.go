@@ -0,0 +1,118 @@
+package dep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	alpm "github.com/Jguer/go-alpm"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// Sizes sums up what a transaction downloads and installs. Net is the change
+// of the installed size of repo packages. AUR packages aren't built yet, so
+// AurCurrent is the installed size of the versions they replace and
+// AurNotInstalled counts the ones without such a version.
+type Sizes struct {
+	Download  int64
+	Installed int64
+	Net       int64
+
+	AurCurrent      int64
+	AurNotInstalled int
+
+	New         int
+	Upgraded    int
+	Reinstalled int
+}
+
+func (s *Sizes) count(version, localVersion string, installed bool) {
+	switch {
+	case !installed:
+		s.New++
+	case version != localVersion:
+		s.Upgraded++
+	default:
+		s.Reinstalled++
+	}
+}
+
+func inCache(fileName string, cacheDirs []string) bool {
+	for _, dir := range cacheDirs {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sizes returns the sizes of the packages of the order compared to the ones
+// installed. Repo packages found in cacheDirs aren't downloaded.
+func (do *Order) Sizes(localDB *alpm.DB, cacheDirs []string) Sizes {
+	var s Sizes
+
+	for _, pkg := range do.Repo {
+		if !inCache(pkg.FileName(), cacheDirs) {
+			s.Download += pkg.Size()
+		}
+		s.Installed += pkg.ISize()
+		s.Net += pkg.ISize()
+
+		local := localDB.Pkg(pkg.Name())
+		if local != nil {
+			s.Net -= local.ISize()
+			s.count(pkg.Version(), local.Version(), true)
+		} else {
+			s.count(pkg.Version(), "", false)
+		}
+	}
+
+	for _, base := range do.Aur {
+		for _, pkg := range base {
+			local := localDB.Pkg(pkg.Name)
+			if local != nil {
+				s.AurCurrent += local.ISize()
+				s.count(pkg.Version, local.Version(), true)
+			} else {
+				s.AurNotInstalled++
+				s.count(pkg.Version, "", false)
+			}
+		}
+	}
+
+	return s
+}
+
+// signedHuman formats a size change with its sign
+func signedHuman(size int64) string {
+	if size < 0 {
+		return "-" + text.Human(-size)
+	}
+
+	return "+" + text.Human(size)
+}
+
+// Print prints the totals of a transaction
+func (s Sizes) Print() {
+	text.OperationInfoln(gotext.Get("Packages: %d new, %d upgraded, %d reinstalled",
+		s.New, s.Upgraded, s.Reinstalled))
+
+	printSize := func(name, size string) {
+		fmt.Printf("    %-24s%s\n", name, size)
+	}
+
+	printSize(gotext.Get("Total Download Size:"), text.Human(s.Download))
+	printSize(gotext.Get("Total Installed Size:"), text.Human(s.Installed))
+	printSize(gotext.Get("Net Upgrade Size:"), signedHuman(s.Net))
+
+	if s.AurCurrent > 0 || s.AurNotInstalled > 0 {
+		aurSize := text.Human(s.AurCurrent)
+		if s.AurNotInstalled > 0 {
+			aurSize += " " + gotext.Get("(%d not installed)", s.AurNotInstalled)
+		}
+		printSize(gotext.Get("AUR Current Size:"), aurSize)
+	}
+}
@@ -0,0 +1,25 @@
+package dep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizesCount(t *testing.T) {
+	var s Sizes
+	s.count("1.0-1", "", false)
+	s.count("1.1-1", "1.0-1", true)
+	s.count("1.0-1", "1.0-1", true)
+	s.count("2.0-1", "", false)
+
+	assert.Equal(t, 2, s.New)
+	assert.Equal(t, 1, s.Upgraded)
+	assert.Equal(t, 1, s.Reinstalled)
+}
+
+func TestSignedHuman(t *testing.T) {
+	assert.Equal(t, "+1.5 KiB", signedHuman(1536))
+	assert.Equal(t, "-2.0 MiB", signedHuman(-2*1024*1024))
+	assert.Equal(t, "+0.0 B", signedHuman(0))
+}
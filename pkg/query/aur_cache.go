@@ -0,0 +1,154 @@
+package query
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+	rpc "github.com/mikkeloscar/aur"
+
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// cacheEntry is the last known info of a package. Pkg is nil for names that
+// aren't in the AUR.
+type cacheEntry struct {
+	Time time.Time `json:"time"`
+	Pkg  *rpc.Pkg  `json:"pkg,omitempty"`
+}
+
+// CacheClient keeps the info answers of another AURClient on disk. Entries
+// younger than TTL are served without asking, older ones are asked for again.
+// When that fails, stale entries are served with a warning instead, so
+// upgrade checks and status commands keep working on a flaky connection. A
+// TTL of 0 always asks and only uses the cache when the AUR can't be
+// reached. Refresh asks for every entry again.
+type CacheClient struct {
+	AURClient
+
+	Path    string
+	TTL     time.Duration
+	Refresh bool
+
+	mux     sync.Mutex
+	loaded  bool
+	entries map[string]cacheEntry
+	warned  bool
+}
+
+func (c *CacheClient) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.entries = make(map[string]cacheEntry)
+
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return
+	}
+
+	// a corrupt cache is as good as an empty one
+	if err = json.Unmarshal(data, &c.entries); err != nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+}
+
+func (c *CacheClient) save() error {
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.Path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.Path)
+}
+
+func (c *CacheClient) Info(names []string) ([]rpc.Pkg, error) {
+	c.mux.Lock()
+	c.load()
+
+	now := time.Now()
+	info := make([]rpc.Pkg, 0, len(names))
+	stale := make([]string, 0)
+
+	for _, name := range names {
+		entry, ok := c.entries[name]
+		if !ok || c.Refresh || now.Sub(entry.Time) >= c.TTL {
+			stale = append(stale, name)
+			continue
+		}
+
+		if entry.Pkg != nil {
+			info = append(info, *entry.Pkg)
+		}
+	}
+	c.mux.Unlock()
+
+	if len(stale) == 0 {
+		return info, nil
+	}
+
+	fetched, err := c.AURClient.Info(stale)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if err != nil {
+		return c.serveStale(info, stale, err)
+	}
+
+	found := make(map[string]bool, len(fetched))
+	for i := range fetched {
+		pkg := fetched[i]
+		found[pkg.Name] = true
+		c.entries[pkg.Name] = cacheEntry{now, &pkg}
+	}
+
+	for _, name := range stale {
+		if !found[name] {
+			c.entries[name] = cacheEntry{Time: now}
+		}
+	}
+
+	if err := c.save(); err != nil {
+		text.Warnln(gotext.Get("failed to save AUR cache '%s': %s", c.Path, err))
+	}
+
+	return append(info, fetched...), nil
+}
+
+// serveStale answers with the cached entries of names after the AUR couldn't
+// be reached. The error is returned when a name was never cached.
+func (c *CacheClient) serveStale(info []rpc.Pkg, names []string, err error) ([]rpc.Pkg, error) {
+	oldest := time.Now()
+
+	for _, name := range names {
+		entry, ok := c.entries[name]
+		if !ok {
+			return info, err
+		}
+
+		if entry.Time.Before(oldest) {
+			oldest = entry.Time
+		}
+		if entry.Pkg != nil {
+			info = append(info, *entry.Pkg)
+		}
+	}
+
+	if !c.warned {
+		c.warned = true
+		text.Warnln(gotext.Get("unable to reach the AUR, using cached data from %s: %s",
+			text.FormatTime(int(oldest.Unix())), err))
+	}
+
+	return info, nil
+}
@@ -0,0 +1,76 @@
+package query
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingClient counts the names it is asked about and fails when down
+type countingClient struct {
+	MemoryClient
+	asked []string
+	down  bool
+}
+
+func (c *countingClient) Info(names []string) ([]rpc.Pkg, error) {
+	if c.down {
+		return nil, errors.New("network is unreachable")
+	}
+
+	c.asked = append(c.asked, names...)
+	return c.MemoryClient.Info(names)
+}
+
+func TestCacheClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-aur-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "aur-info.json")
+	inner := &countingClient{MemoryClient: MemoryClient{Pkgs: clientPkgs}}
+	client := &CacheClient{AURClient: inner, Path: path, TTL: time.Hour}
+
+	info, err := client.Info([]string{"yay", "missing"})
+	assert.NoError(t, err)
+	assert.Len(t, info, 1)
+	assert.Equal(t, []string{"yay", "missing"}, inner.asked)
+
+	// fresh entries, missing names included, are served from disk
+	inner.asked = nil
+	client = &CacheClient{AURClient: inner, Path: path, TTL: time.Hour}
+	info, err = client.Info([]string{"yay", "missing", "paru"})
+	assert.NoError(t, err)
+	assert.Len(t, info, 2)
+	assert.Equal(t, []string{"paru"}, inner.asked)
+
+	// refresh asks again
+	inner.asked = nil
+	client = &CacheClient{AURClient: inner, Path: path, TTL: time.Hour, Refresh: true}
+	_, err = client.Info([]string{"yay"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"yay"}, inner.asked)
+
+	// without a TTL the AUR is always asked while it is up
+	inner.asked = nil
+	client = &CacheClient{AURClient: inner, Path: path, TTL: 0}
+	_, err = client.Info([]string{"yay"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"yay"}, inner.asked)
+
+	// stale entries are served when the AUR is down, unknown names fail
+	inner.down = true
+	client = &CacheClient{AURClient: inner, Path: path, TTL: 0}
+	info, err = client.Info([]string{"yay", "paru"})
+	assert.NoError(t, err)
+	assert.Len(t, info, 2)
+
+	_, err = client.Info([]string{"yay-bin"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,321 @@
+package query
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/leonelquinteros/gotext"
+	rpc "github.com/mikkeloscar/aur"
+
+	"github.com/Jguer/yay/v10/pkg/aurhttp"
+)
+
+// AURClient is a source of AUR package metadata and build files
+type AURClient interface {
+	// Info returns the packages with the given names. Names that don't
+	// exist are left out.
+	Info(names []string) ([]rpc.Pkg, error)
+	// Search returns the packages matching query by the given field
+	Search(query string, by rpc.By) ([]rpc.Pkg, error)
+	// PKGBUILD returns the current PKGBUILD of a pkgbase
+	PKGBUILD(pkgbase string) ([]byte, error)
+	// CloneURL returns the git URL the snapshot of a pkgbase is fetched from
+	CloneURL(pkgbase string) string
+}
+
+// Client is the AURClient every AUR query goes through
+var Client AURClient = &RPCClient{URL: "https://aur.archlinux.org"}
+
+var errQueryTooSmall = errors.New("query arg too small")
+
+func cloneURL(base, pkgbase string) string {
+	return strings.TrimRight(base, "/") + "/" + pkgbase + ".git"
+}
+
+// RPCClient queries the RPC interface of an aurweb instance
+type RPCClient struct {
+	URL string
+}
+
+type rpcResponse struct {
+	Error   string    `json:"error"`
+	Results []rpc.Pkg `json:"results"`
+}
+
+func (c *RPCClient) get(values url.Values) ([]rpc.Pkg, error) {
+	values.Set("v", "5")
+
+	body, err := aurhttp.Default.Get(strings.TrimRight(c.URL, "/") + "/rpc.php?" + values.Encode())
+	if statusErr, ok := err.(*aurhttp.StatusError); ok && statusErr.Code >= 500 {
+		return nil, rpc.ErrServiceUnavailable
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := new(rpcResponse)
+	if err = json.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
+
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+
+	return result.Results, nil
+}
+
+func (c *RPCClient) Info(names []string) ([]rpc.Pkg, error) {
+	values := url.Values{}
+	values.Set("type", "info")
+	for _, name := range names {
+		values.Add("arg[]", name)
+	}
+
+	return c.get(values)
+}
+
+func (c *RPCClient) Search(query string, by rpc.By) ([]rpc.Pkg, error) {
+	values := url.Values{}
+	values.Set("type", "search")
+	values.Set("arg", query)
+	values.Set("by", by.String())
+
+	return c.get(values)
+}
+
+func (c *RPCClient) PKGBUILD(pkgbase string) ([]byte, error) {
+	values := url.Values{}
+	values.Set("h", pkgbase)
+
+	pkgbuild, err := aurhttp.Default.Get(strings.TrimRight(c.URL, "/") + "/cgit/aur.git/plain/PKGBUILD?" + values.Encode())
+	if statusErr, ok := err.(*aurhttp.StatusError); ok {
+		return nil, fmt.Errorf("error code %d for package %s", statusErr.Code, pkgbase)
+	}
+
+	return pkgbuild, err
+}
+
+func (c *RPCClient) CloneURL(pkgbase string) string {
+	return cloneURL(c.URL, pkgbase)
+}
+
+// depName strips the version requirement and the description of a
+// dependency
+func depName(dep string) string {
+	if i := strings.Index(dep, ":"); i >= 0 {
+		dep = dep[:i]
+	}
+	if i := strings.IndexAny(dep, "<>="); i >= 0 {
+		dep = dep[:i]
+	}
+
+	return strings.TrimSpace(dep)
+}
+
+// searchPkgs searches pkgs the way the RPC does: names and descriptions by
+// case insensitive substrings, maintainers and dependencies by their exact
+// names
+func searchPkgs(pkgs []rpc.Pkg, query string, by rpc.By) ([]rpc.Pkg, error) {
+	if (by == rpc.Name || by == rpc.NameDesc) && len(query) < 2 {
+		return nil, errQueryTooSmall
+	}
+
+	lowerQuery := strings.ToLower(query)
+	hasDep := func(deps []string) bool {
+		for _, dep := range deps {
+			if depName(dep) == query {
+				return true
+			}
+		}
+		return false
+	}
+
+	results := make([]rpc.Pkg, 0)
+	for i := range pkgs {
+		pkg := &pkgs[i]
+
+		var match bool
+		switch by {
+		case rpc.Name:
+			match = strings.Contains(strings.ToLower(pkg.Name), lowerQuery)
+		case rpc.Maintainer:
+			match = pkg.Maintainer == query
+		case rpc.Depends:
+			match = hasDep(pkg.Depends)
+		case rpc.MakeDepends:
+			match = hasDep(pkg.MakeDepends)
+		case rpc.OptDepends:
+			match = hasDep(pkg.OptDepends)
+		case rpc.CheckDepends:
+			match = hasDep(pkg.CheckDepends)
+		default:
+			match = strings.Contains(strings.ToLower(pkg.Name), lowerQuery) ||
+				strings.Contains(strings.ToLower(pkg.Description), lowerQuery)
+		}
+
+		if match {
+			results = append(results, *pkg)
+		}
+	}
+
+	return results, nil
+}
+
+// MemoryClient serves packages held in memory. It stands in for the AUR in
+// tests.
+type MemoryClient struct {
+	Pkgs      []rpc.Pkg
+	PKGBUILDs map[string]string
+	GitURL    string
+}
+
+func (c *MemoryClient) Info(names []string) ([]rpc.Pkg, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	info := make([]rpc.Pkg, 0, len(names))
+	for i := range c.Pkgs {
+		if wanted[c.Pkgs[i].Name] {
+			info = append(info, c.Pkgs[i])
+		}
+	}
+
+	return info, nil
+}
+
+func (c *MemoryClient) Search(query string, by rpc.By) ([]rpc.Pkg, error) {
+	return searchPkgs(c.Pkgs, query, by)
+}
+
+func (c *MemoryClient) PKGBUILD(pkgbase string) ([]byte, error) {
+	pkgbuild, ok := c.PKGBUILDs[pkgbase]
+	if !ok {
+		return nil, fmt.Errorf("error code %d for package %s", http.StatusNotFound, pkgbase)
+	}
+
+	return []byte(pkgbuild), nil
+}
+
+func (c *MemoryClient) CloneURL(pkgbase string) string {
+	return cloneURL(c.GitURL, pkgbase)
+}
+
+// DumpClient serves the packages of a metadata dump of the AUR, such as
+// packages-meta-ext-v1.json.gz, and fetches build files from a git mirror
+type DumpClient struct {
+	Path   string
+	GitURL string
+	GitBin string
+
+	once   sync.Once
+	err    error
+	pkgs   []rpc.Pkg
+	byName map[string]int
+}
+
+// readDump reads a metadata dump of the AUR, gzipped when its name ends
+// with .gz
+func readDump(path string) ([]rpc.Pkg, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(gotext.Get("failed to open AUR metadata '%s': %s", path, err))
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, errors.New(gotext.Get("failed to read AUR metadata '%s': %s", path, err))
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	return decodeDump(reader, path)
+}
+
+func decodeDump(reader io.Reader, path string) ([]rpc.Pkg, error) {
+	pkgs := make([]rpc.Pkg, 0)
+	if err := json.NewDecoder(reader).Decode(&pkgs); err != nil {
+		return nil, errors.New(gotext.Get("failed to read AUR metadata '%s': %s", path, err))
+	}
+
+	return pkgs, nil
+}
+
+func (c *DumpClient) load() error {
+	c.once.Do(func() {
+		c.pkgs, c.err = readDump(c.Path)
+		if c.err != nil {
+			return
+		}
+
+		c.byName = make(map[string]int, len(c.pkgs))
+		for i := range c.pkgs {
+			c.byName[c.pkgs[i].Name] = i
+		}
+	})
+
+	return c.err
+}
+
+func (c *DumpClient) Info(names []string) ([]rpc.Pkg, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	info := make([]rpc.Pkg, 0, len(names))
+	for _, name := range names {
+		if i, ok := c.byName[name]; ok {
+			info = append(info, c.pkgs[i])
+		}
+	}
+
+	return info, nil
+}
+
+func (c *DumpClient) Search(query string, by rpc.By) ([]rpc.Pkg, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return searchPkgs(c.pkgs, query, by)
+}
+
+// PKGBUILD reads the PKGBUILD from a shallow clone of the mirror
+func (c *DumpClient) PKGBUILD(pkgbase string) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "yay-pkgbuild")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	gitBin := c.GitBin
+	if gitBin == "" {
+		gitBin = "git"
+	}
+
+	cmd := exec.Command(gitBin, "clone", "--quiet", "--depth", "1", "--no-checkout", c.CloneURL(pkgbase), dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %s", pkgbase, strings.TrimSpace(string(out)))
+	}
+
+	return exec.Command(gitBin, "-C", dir, "show", "HEAD:PKGBUILD").Output()
+}
+
+func (c *DumpClient) CloneURL(pkgbase string) string {
+	return cloneURL(c.GitURL, pkgbase)
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+var clientPkgs = []rpc.Pkg{
+	{Name: "yay", PackageBase: "yay", Description: "Yet another yogurt", Maintainer: "jguer",
+		MakeDepends: []string{"go>=1.14"}},
+	{Name: "yay-bin", PackageBase: "yay-bin", Description: "Yet another yogurt (binary)", Maintainer: "jguer"},
+	{Name: "paru", PackageBase: "paru", Description: "Feature packed AUR helper",
+		Depends: []string{"git", "pacman: for the database"}},
+}
+
+func TestSearchPkgs(t *testing.T) {
+	names := func(pkgs []rpc.Pkg) []string {
+		names := make([]string, 0, len(pkgs))
+		for i := range pkgs {
+			names = append(names, pkgs[i].Name)
+		}
+		return names
+	}
+
+	testCases := []struct {
+		query string
+		by    rpc.By
+		want  []string
+	}{
+		{"YAY", rpc.Name, []string{"yay", "yay-bin"}},
+		{"helper", rpc.Name, []string{}},
+		{"helper", rpc.NameDesc, []string{"paru"}},
+		{"jguer", rpc.Maintainer, []string{"yay", "yay-bin"}},
+		{"", rpc.Maintainer, []string{"paru"}},
+		{"go", rpc.MakeDepends, []string{"yay"}},
+		{"pacman", rpc.Depends, []string{"paru"}},
+	}
+
+	for _, tc := range testCases {
+		results, err := searchPkgs(clientPkgs, tc.query, tc.by)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, names(results), tc.query)
+	}
+
+	_, err := searchPkgs(clientPkgs, "y", rpc.NameDesc)
+	assert.Error(t, err)
+}
+
+func TestDumpClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-dump")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "packages-meta-ext-v1.json.gz")
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	assert.NoError(t, json.NewEncoder(gz).Encode(clientPkgs))
+	assert.NoError(t, gz.Close())
+	assert.NoError(t, file.Close())
+
+	client := &DumpClient{Path: path, GitURL: "https://aur.example.org/"}
+	info, err := client.Info([]string{"paru", "missing", "yay"})
+	assert.NoError(t, err)
+	assert.Len(t, info, 2)
+	assert.Equal(t, "paru", info[0].Name)
+	assert.Equal(t, []string{"go>=1.14"}, info[1].MakeDepends)
+
+	assert.Equal(t, "https://aur.example.org/yay.git", client.CloneURL("yay"))
+
+	_, err = (&DumpClient{Path: filepath.Join(dir, "missing.json")}).Info([]string{"yay"})
+	assert.Error(t, err)
+}
+
+func TestAURInfoMemoryClient(t *testing.T) {
+	defer func(client AURClient) { Client = client }(Client)
+	Client = &MemoryClient{Pkgs: clientPkgs}
+
+	warnings := &AURWarnings{}
+	info, err := AURInfo([]string{"yay", "paru", "missing"}, warnings, 2)
+	assert.NoError(t, err)
+	assert.Len(t, info, 2)
+	assert.Equal(t, []string{"missing"}, warnings.Missing)
+	assert.Equal(t, []string{"paru"}, warnings.Orphans)
+}
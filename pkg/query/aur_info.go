@@ -25,7 +25,7 @@ func AURInfo(names []string, warnings *AURWarnings, splitN int) ([]*rpc.Pkg, err
 
 	makeRequest := func(n, max int) {
 		defer wg.Done()
-		tempInfo, requestErr := rpc.Info(names[n:max])
+		tempInfo, requestErr := Client.Info(names[n:max])
 		errs.Add(requestErr)
 		if requestErr != nil {
 			return
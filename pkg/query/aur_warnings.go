@@ -39,7 +39,7 @@ func (warnings *AURWarnings) Print() {
 
 func printRange(names []string) {
 	for _, name := range names {
-		fmt.Print("  " + text.Cyan(name))
+		fmt.Fprint(text.Out(), "  "+text.Cyan(name))
 	}
-	fmt.Println()
+	fmt.Fprintln(text.Out())
 }
@@ -0,0 +1,188 @@
+package query
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+	rpc "github.com/mikkeloscar/aur"
+
+	"github.com/Jguer/yay/v10/pkg/aurhttp"
+)
+
+// metadataDumpName is the name of the AUR's dump of every package
+const metadataDumpName = "packages-meta-ext-v1.json.gz"
+
+// SearchIndex searches a local copy of the metadata of every AUR package
+type SearchIndex struct {
+	Pkgs []rpc.Pkg
+}
+
+// UpdateSearchIndex downloads the metadata dump of the AUR and stores it as
+// an index at path when the index is older than interval days, or when force
+// is set. An interval of -1 never refreshes an existing index.
+func UpdateSearchIndex(aurURL, path string, interval int, force bool) error {
+	info, err := os.Stat(path)
+	if err == nil && !force && (interval == -1 || time.Since(info.ModTime()).Hours() < float64(interval*24)) {
+		return nil
+	}
+
+	body, err := aurhttp.Default.Get(strings.TrimRight(aurURL, "/") + "/" + metadataDumpName)
+	if err != nil {
+		return err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return errors.New(gotext.Get("failed to read AUR metadata '%s': %s", metadataDumpName, err))
+	}
+	defer gz.Close()
+
+	pkgs, err := decodeDump(gz, metadataDumpName)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err = gob.NewEncoder(out).Encode(&SearchIndex{pkgs}); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// LoadSearchIndex reads an index stored by UpdateSearchIndex
+func LoadSearchIndex(path string) (*SearchIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := &SearchIndex{}
+	if err = gob.NewDecoder(file).Decode(index); err != nil {
+		return nil, errors.New(gotext.Get("failed to read search index '%s': %s", path, err))
+	}
+
+	return index, nil
+}
+
+// searchFields returns the values of a package a search by the given field
+// looks at. Dependencies are matched by name only.
+func searchFields(pkg *rpc.Pkg, by string) []string {
+	depNames := func(deps []string) []string {
+		names := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			names = append(names, depName(dep))
+		}
+		return names
+	}
+
+	switch by {
+	case "name":
+		return []string{pkg.Name}
+	case "description":
+		return []string{pkg.Description}
+	case "keywords":
+		return pkg.Keywords
+	case "maintainer":
+		return []string{pkg.Maintainer}
+	case "depends":
+		return depNames(pkg.Depends)
+	case "makedepends":
+		return depNames(pkg.MakeDepends)
+	case "optdepends":
+		return depNames(pkg.OptDepends)
+	case "checkdepends":
+		return depNames(pkg.CheckDepends)
+	case "provides":
+		return append([]string{pkg.Name}, depNames(pkg.Provides)...)
+	}
+
+	return append([]string{pkg.Name, pkg.Description}, pkg.Keywords...)
+}
+
+// compileTerm compiles a search term as a case insensitive regular
+// expression, or as a plain string if it isn't a valid one
+func compileTerm(term string) *regexp.Regexp {
+	if re, err := regexp.Compile("(?i)" + term); err == nil {
+		return re
+	}
+
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(term))
+}
+
+// Search returns the packages matching the terms by the given field. Like
+// pacman's, terms are regular expressions that all have to match. OR between
+// terms separates alternatives, of which one has to match.
+func (index *SearchIndex) Search(terms []string, by string) []rpc.Pkg {
+	alternatives := make([][]*regexp.Regexp, 0, 1)
+	current := make([]*regexp.Regexp, 0, len(terms))
+
+	for _, term := range terms {
+		if term == "OR" {
+			alternatives = append(alternatives, current)
+			current = make([]*regexp.Regexp, 0, len(terms))
+			continue
+		}
+		current = append(current, compileTerm(term))
+	}
+	alternatives = append(alternatives, current)
+
+	matchesAll := func(fields []string, res []*regexp.Regexp) bool {
+		if len(res) == 0 {
+			return false
+		}
+
+		for _, re := range res {
+			found := false
+			for _, field := range fields {
+				if re.MatchString(field) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	results := make([]rpc.Pkg, 0)
+	for i := range index.Pkgs {
+		fields := searchFields(&index.Pkgs[i], by)
+		for _, res := range alternatives {
+			if matchesAll(fields, res) {
+				results = append(results, index.Pkgs[i])
+				break
+			}
+		}
+	}
+
+	return results
+}
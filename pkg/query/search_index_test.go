@@ -0,0 +1,85 @@
+package query
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rpc "github.com/mikkeloscar/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchIndexSearch(t *testing.T) {
+	index := &SearchIndex{Pkgs: []rpc.Pkg{
+		{Name: "yay", Description: "Yet another yogurt", Keywords: []string{"helper", "pacman"},
+			MakeDepends: []string{"go>=1.14"}, Maintainer: "jguer"},
+		{Name: "yay-bin", Description: "Yet another yogurt (binary)", Provides: []string{"yay=10.0.2"}},
+		{Name: "paru", Description: "Feature packed AUR helper", Depends: []string{"git", "pacman"}},
+		{Name: "go-tools", Description: "Developer tools for go"},
+	}}
+
+	names := func(pkgs []rpc.Pkg) []string {
+		names := make([]string, 0, len(pkgs))
+		for i := range pkgs {
+			names = append(names, pkgs[i].Name)
+		}
+		return names
+	}
+
+	testCases := []struct {
+		terms []string
+		by    string
+		want  []string
+	}{
+		{[]string{"yogurt"}, "name-desc", []string{"yay", "yay-bin"}},
+		{[]string{"helper"}, "name-desc", []string{"yay", "paru"}},
+		{[]string{"helper"}, "keywords", []string{"yay"}},
+		{[]string{"helper"}, "description", []string{"paru"}},
+		{[]string{"yogurt", "binary"}, "", []string{"yay-bin"}},
+		{[]string{"^yay$", "OR", "paru"}, "name", []string{"yay", "paru"}},
+		{[]string{"^yay$"}, "provides", []string{"yay", "yay-bin"}},
+		{[]string{"^go$"}, "makedepends", []string{"yay"}},
+		{[]string{"pacman"}, "depends", []string{"paru"}},
+		{[]string{"JGUER"}, "maintainer", []string{"yay"}},
+		{[]string{"c++"}, "name", []string{}},
+		{[]string{"OR"}, "name", []string{}},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, names(index.Search(tc.terms, tc.by)), tc.terms)
+	}
+}
+
+func TestUpdateSearchIndex(t *testing.T) {
+	var dump bytes.Buffer
+	gz := gzip.NewWriter(&dump)
+	assert.NoError(t, json.NewEncoder(gz).Encode(clientPkgs))
+	assert.NoError(t, gz.Close())
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/packages-meta-ext-v1.json.gz", r.URL.Path)
+		_, _ = w.Write(dump.Bytes())
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "yay-index")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "aur-index.gob")
+
+	assert.NoError(t, UpdateSearchIndex(server.URL, path, 7, false))
+	assert.NoError(t, UpdateSearchIndex(server.URL, path, 7, false))
+	assert.Equal(t, 1, requests)
+
+	index, err := LoadSearchIndex(path)
+	assert.NoError(t, err)
+	assert.Equal(t, clientPkgs, index.Pkgs)
+}
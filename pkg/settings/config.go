@@ -16,9 +16,20 @@ const (
 // HideMenus indicates if pacman's provider menus must be hidden
 var HideMenus = false
 
+// ProviderRule chooses between the providers of the dependencies matching
+// the glob pattern Dep. Providers matching a pattern of Avoid are not
+// offered unless all of them match, and the first provider matching a
+// pattern of Prefer is picked without asking.
+type ProviderRule struct {
+	Dep    string   `json:"dep"`
+	Prefer []string `json:"prefer,omitempty"`
+	Avoid  []string `json:"avoid,omitempty"`
+}
+
 // Configuration stores yay's config.
 type Configuration struct {
 	AURURL             string   `json:"aururl"`
+	AURMetadata        string   `json:"aurmetadata"`
 	BuildDir           string   `json:"buildDir"`
 	ABSDir             string   `json:"absdir"`
 	Editor             string   `json:"editor"`
@@ -41,12 +52,17 @@ type Configuration struct {
 	SearchBy           string   `json:"searchby"`
 	GitFlags           string   `json:"gitflags"`
 	RemoveMake         string   `json:"removemake"`
+	ScanThreshold      string   `json:"scanthreshold"`
 	SudoBin            string   `json:"sudobin"`
 	SudoFlags          string   `json:"sudoflags"`
 	RequestSplitN      int      `json:"requestsplitn"`
+	BuildJobs          int      `json:"buildjobs"`
+	DownloadJobs       int      `json:"downloadjobs"`
+	BuildLogs          int      `json:"buildlogs"`
 	SearchMode         int      `json:"-"`
 	SortMode           int      `json:"sortmode"`
 	CompletionInterval int      `json:"completionrefreshtime"`
+	AURCacheTime       int      `json:"aurcachetime"`
 	SudoLoop           bool     `json:"sudoloop"`
 	TimeUpdate         bool     `json:"timeupdate"`
 	NoConfirm          bool     `json:"-"`
@@ -61,10 +77,20 @@ type Configuration struct {
 	CombinedUpgrade    bool     `json:"combinedupgrade"`
 	UseAsk             bool     `json:"useask"`
 	BatchInstall       bool     `json:"batchinstall"`
+	SearchIndex        bool     `json:"searchindex"`
+	KeepGoing          bool     `json:"keepgoing"`
+	Chroot             bool     `json:"chroot"`
+	ChrootDir          string   `json:"chrootdir"`
+	LocalRepo          bool     `json:"localrepo"`
+	LocalRepoName      string   `json:"localreponame"`
+	LocalRepoDir       string   `json:"localrepodir"`
 	Runtime            *Runtime `json:"-"`
 	Fuzzy              bool     `json:"fuzzy"`
 	LangCheck          bool     `json:"langcheck"`
 	SourceCheck        bool     `json:"srccheck"`
+
+	// ProviderRules are applied in order
+	ProviderRules []ProviderRule `json:"providerrules"`
 }
 
 // SaveConfig writes yay config to file.
@@ -88,8 +114,12 @@ func (config *Configuration) SaveConfig(configPath string) error {
 
 func (config *Configuration) ExpandEnv() {
 	config.AURURL = os.ExpandEnv(config.AURURL)
+	config.AURMetadata = os.ExpandEnv(config.AURMetadata)
 	config.ABSDir = os.ExpandEnv(config.ABSDir)
 	config.BuildDir = os.ExpandEnv(config.BuildDir)
+	config.ChrootDir = os.ExpandEnv(config.ChrootDir)
+	config.LocalRepoName = os.ExpandEnv(config.LocalRepoName)
+	config.LocalRepoDir = os.ExpandEnv(config.LocalRepoDir)
 	config.Editor = os.ExpandEnv(config.Editor)
 	config.EditorFlags = os.ExpandEnv(config.EditorFlags)
 	config.MakepkgBin = os.ExpandEnv(config.MakepkgBin)
@@ -144,6 +174,7 @@ func MakeConfig() *Configuration {
 		GitFlags:           "",
 		SortMode:           BottomUp,
 		CompletionInterval: 7,
+		AURCacheTime:       60,
 		SortBy:             "votes",
 		SearchBy:           "name-desc",
 		SudoLoop:           false,
@@ -153,14 +184,25 @@ func MakeConfig() *Configuration {
 		SudoFlags:          "",
 		TimeUpdate:         false,
 		RequestSplitN:      150,
+		BuildJobs:          1,
+		DownloadJobs:       4,
+		BuildLogs:          5,
 		ReDownload:         "no",
 		ReBuild:            "no",
 		BatchInstall:       false,
+		SearchIndex:        false,
+		KeepGoing:          false,
+		Chroot:             false,
+		ChrootDir:          "$HOME/.cache/yay/chroot",
+		LocalRepo:          false,
+		LocalRepoName:      "aur",
+		LocalRepoDir:       "$HOME/.cache/yay/repo",
 		AnswerClean:        "",
 		AnswerDiff:         "",
 		AnswerEdit:         "",
 		AnswerUpgrade:      "",
 		RemoveMake:         "ask",
+		ScanThreshold:      "high",
 		Provides:           true,
 		UpgradeMenu:        true,
 		CleanMenu:          true,
@@ -168,6 +210,7 @@ func MakeConfig() *Configuration {
 		EditMenu:           false,
 		UseAsk:             false,
 		CombinedUpgrade:    false,
+		ProviderRules:      []ProviderRule{},
 	}
 
 	if os.Getenv("XDG_CACHE_HOME") != "" {
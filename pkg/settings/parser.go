@@ -129,6 +129,9 @@ func (a *Arguments) NeedRoot(runtime *Runtime) bool {
 		}
 		return true
 	case "S", "sync":
+		if a.ExistsArg("print-plan") {
+			return false
+		}
 		if a.ExistsArg("y", "refresh") {
 			return true
 		}
@@ -329,6 +332,7 @@ func isArg(arg string) bool {
 	case "noscriptlet":
 	case "p", "print":
 	case "print-format":
+	case "print-plan":
 	case "asdeps":
 	case "asexplicit":
 	case "ignore":
@@ -363,6 +367,7 @@ func isArg(arg string) bool {
 	case "machinereadable":
 	// yay options
 	case "aururl":
+	case "aurmetadata":
 	case "save":
 	case "afterclean", "cleanafter":
 	case "noafterclean", "nocleanafter":
@@ -373,6 +378,8 @@ func isArg(arg string) bool {
 	case "topdown":
 	case "bottomup":
 	case "completioninterval":
+	case "aurcachetime":
+	case "refresh-aur":
 	case "sortby":
 	case "searchby":
 	case "redownload":
@@ -384,6 +391,17 @@ func isArg(arg string) bool {
 	case "norebuild":
 	case "batchinstall":
 	case "nobatchinstall":
+	case "searchindex":
+	case "nosearchindex":
+	case "keepgoing", "keep-going":
+	case "nokeepgoing":
+	case "chroot":
+	case "nochroot":
+	case "chrootdir":
+	case "localrepo":
+	case "nolocalrepo":
+	case "localreponame":
+	case "localrepodir":
 	case "answerclean":
 	case "noanswerclean":
 	case "answerdiff":
@@ -407,6 +425,10 @@ func isArg(arg string) bool {
 	case "sudo":
 	case "sudoflags":
 	case "requestsplitn":
+	case "buildjobs":
+	case "downloadjobs":
+	case "buildlogs":
+	case "scanthreshold":
 	case "sudoloop":
 	case "nosudoloop":
 	case "provides":
@@ -439,7 +461,23 @@ func isArg(arg string) bool {
 	case "complete":
 	case "stats":
 	case "news":
+	case "log":
+	case "history":
+	case "graph":
+	case "why":
+	case "rebuild-check":
+	case "json":
+	case "format":
 	case "gendb":
+	case "resume":
+	case "sync-file":
+	case "export":
+	case "prune":
+	case "pin":
+	case "unpin":
+	case "trust":
+	case "trustmaintainer":
+	case "untrust":
 	case "currentconfig":
 	default:
 		return false
@@ -452,6 +490,8 @@ func handleConfig(config *Configuration, option, value string) bool {
 	switch option {
 	case "aururl":
 		config.AURURL = value
+	case "aurmetadata":
+		config.AURMetadata = value
 	case "save":
 		config.Runtime.SaveConfig = true
 	case "afterclean", "cleanafter":
@@ -475,6 +515,17 @@ func handleConfig(config *Configuration, option, value string) bool {
 		if err == nil {
 			config.CompletionInterval = n
 		}
+	case "aurcachetime":
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			config.AURCacheTime = n
+		}
+	case "refresh-aur":
+		config.Runtime.RefreshAUR = true
+	case "json":
+		config.Runtime.JSON = true
+	case "format":
+		config.Runtime.JSON = value == "json"
 	case "sortby":
 		config.SortBy = value
 	case "searchby":
@@ -501,6 +552,28 @@ func handleConfig(config *Configuration, option, value string) bool {
 		config.BatchInstall = true
 	case "nobatchinstall":
 		config.BatchInstall = false
+	case "searchindex":
+		config.SearchIndex = true
+	case "nosearchindex":
+		config.SearchIndex = false
+	case "keepgoing", "keep-going":
+		config.KeepGoing = true
+	case "nokeepgoing":
+		config.KeepGoing = false
+	case "chroot":
+		config.Chroot = true
+	case "nochroot":
+		config.Chroot = false
+	case "chrootdir":
+		config.ChrootDir = value
+	case "localrepo":
+		config.LocalRepo = true
+	case "nolocalrepo":
+		config.LocalRepo = false
+	case "localreponame":
+		config.LocalRepoName = value
+	case "localrepodir":
+		config.LocalRepoDir = value
 	case "answerclean":
 		config.AnswerClean = value
 	case "noanswerclean":
@@ -552,6 +625,26 @@ func handleConfig(config *Configuration, option, value string) bool {
 		if err == nil && n > 0 {
 			config.RequestSplitN = n
 		}
+	case "buildjobs":
+		n, err := strconv.Atoi(value)
+		if err == nil && n > 0 {
+			config.BuildJobs = n
+		}
+	case "downloadjobs":
+		n, err := strconv.Atoi(value)
+		if err == nil && n > 0 {
+			config.DownloadJobs = n
+		}
+	case "buildlogs":
+		n, err := strconv.Atoi(value)
+		if err == nil && n >= 0 {
+			config.BuildLogs = n
+		}
+	case "scanthreshold":
+		switch value {
+		case "none", "low", "medium", "high":
+			config.ScanThreshold = value
+		}
 	case "sudoloop":
 		config.SudoLoop = true
 	case "nosudoloop":
@@ -680,11 +773,16 @@ func hasParam(arg string) bool {
 	case "color":
 	// yay params
 	case "aururl":
+	case "aurmetadata":
 	case "mflags":
 	case "gpgflags":
 	case "gitflags":
 	case "builddir":
 	case "absdir":
+	case "chrootdir":
+	case "localreponame":
+	case "localrepodir":
+	case "sync-file":
 	case "editor":
 	case "editorflags":
 	case "makepkg":
@@ -695,13 +793,19 @@ func hasParam(arg string) bool {
 	case "sudo":
 	case "sudoflags":
 	case "requestsplitn":
+	case "buildjobs":
+	case "downloadjobs":
+	case "buildlogs":
+	case "scanthreshold":
 	case "answerclean":
 	case "answerdiff":
 	case "answeredit":
 	case "answerupgrade":
 	case "completioninterval":
+	case "aurcachetime":
 	case "sortby":
 	case "searchby":
+	case "format":
 	default:
 		return false
 	}
@@ -834,6 +938,10 @@ func (a *Arguments) ParseCommandLine(config *Configuration) error {
 		os.Stdin = file
 	}
 
+	if format, _, exists := a.GetArg("format"); exists && format != "json" && format != "text" {
+		return errors.New(gotext.Get("invalid output format '%s'", format))
+	}
+
 	a.extractYayOptions(config)
 	return nil
 }
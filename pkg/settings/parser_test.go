@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -306,3 +307,18 @@ func Test_isArg(t *testing.T) {
 	got = isArg("dbpath")
 	assert.True(t, got)
 }
+
+func TestArguments_ParseCommandLineFormat(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	config := MakeConfig()
+	config.Runtime = &Runtime{}
+	os.Args = []string{"yay", "-Ss", "--format", "json", "foo"}
+	assert.NoError(t, MakeArguments().ParseCommandLine(config))
+	assert.True(t, config.Runtime.JSON)
+
+	config.Runtime = &Runtime{}
+	os.Args = []string{"yay", "-Ss", "--format=yaml", "foo"}
+	assert.EqualError(t, MakeArguments().ParseCommandLine(config), "invalid output format 'yaml'")
+	assert.False(t, config.Runtime.JSON)
+}
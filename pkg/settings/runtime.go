@@ -20,6 +20,30 @@ const vcsFileName string = "vcs.json"
 
 const completionFileName string = "completion.cache"
 
+// journalFileName holds the name of the transaction journal file.
+const journalFileName string = "transaction.json"
+
+// pinsFileName holds the name of the file with pinned AUR packages.
+const pinsFileName string = "pins.json"
+
+// historyFileName holds the name of the transaction history file.
+const historyFileName string = "history.jsonl"
+
+// trustFileName holds the name of the file with trusted AUR maintainers.
+const trustFileName string = "trust.json"
+
+// aurCacheFileName holds the name of the cache of AUR package info.
+const aurCacheFileName string = "aur-info.json"
+
+// searchIndexFileName holds the name of the offline AUR search index.
+const searchIndexFileName string = "aur-index.gob"
+
+// hooksDirName holds the name of the directory with user hooks.
+const hooksDirName string = "hooks"
+
+// logsDirName holds the name of the directory with build logs.
+const logsDirName string = "logs"
+
 const (
 	ModeAny TargetMode = iota
 	ModeAUR
@@ -32,6 +56,16 @@ type Runtime struct {
 	CompletionPath string
 	ConfigPath     string
 	VCSPath        string
+	JournalPath    string
+	HooksPath      string
+	LogsPath       string
+	PinsPath       string
+	HistoryPath    string
+	TrustPath      string
+	AURCachePath   string
+	AURIndexPath   string
+	RefreshAUR     bool
+	JSON           bool
 	PacmanConf     *pacmanconf.Config
 	AlpmHandle     *alpm.Handle
 }
@@ -39,6 +73,7 @@ type Runtime struct {
 func MakeRuntime() (*Runtime, error) {
 	cacheHome := ""
 	configHome := ""
+	stateHome := ""
 
 	runtime := &Runtime{
 		Mode:           ModeAny,
@@ -70,9 +105,27 @@ func MakeRuntime() (*Runtime, error) {
 		return runtime, err
 	}
 
+	// build logs stay out of the cache, BuildDir defaults to it and -Sc
+	// removes everything in there that isn't a pkgbase
+	if stateHome = os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		stateHome = filepath.Join(stateHome, "yay")
+	} else if stateHome = os.Getenv("HOME"); stateHome != "" {
+		stateHome = filepath.Join(stateHome, ".local", "state", "yay")
+	} else {
+		return nil, errors.New(gotext.Get("%s and %s unset", "XDG_STATE_HOME", "HOME"))
+	}
+
 	runtime.ConfigPath = filepath.Join(configHome, configFileName)
+	runtime.HooksPath = filepath.Join(configHome, hooksDirName)
+	runtime.PinsPath = filepath.Join(configHome, pinsFileName)
+	runtime.TrustPath = filepath.Join(configHome, trustFileName)
 	runtime.VCSPath = filepath.Join(cacheHome, vcsFileName)
 	runtime.CompletionPath = filepath.Join(cacheHome, completionFileName)
+	runtime.JournalPath = filepath.Join(cacheHome, journalFileName)
+	runtime.HistoryPath = filepath.Join(cacheHome, historyFileName)
+	runtime.AURCachePath = filepath.Join(cacheHome, aurCacheFileName)
+	runtime.AURIndexPath = filepath.Join(cacheHome, searchIndexFileName)
+	runtime.LogsPath = filepath.Join(stateHome, logsDirName)
 
 	return runtime, nil
 }
@@ -2,6 +2,7 @@ package text
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/leonelquinteros/gotext"
@@ -13,14 +14,32 @@ const (
 	opSymbol   = "::"
 )
 
+// output is where messages go, stdout unless SetOutput changed it
+var output io.Writer
+
+// SetOutput sends messages to w instead of stdout. Commands that print
+// machine readable output on stdout send them to stderr.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// Out returns where messages go
+func Out() io.Writer {
+	if output == nil {
+		return os.Stdout
+	}
+
+	return output
+}
+
 func OperationInfoln(a ...interface{}) {
-	fmt.Fprint(os.Stdout, append([]interface{}{Bold(Cyan(opSymbol + " ")), boldCode}, a...)...)
-	fmt.Fprintln(os.Stdout, ResetCode)
+	fmt.Fprint(Out(), append([]interface{}{Bold(Cyan(opSymbol + " ")), boldCode}, a...)...)
+	fmt.Fprintln(Out(), ResetCode)
 }
 
 func OperationInfo(a ...interface{}) {
-	fmt.Fprint(os.Stdout, append([]interface{}{Bold(Cyan(opSymbol + " ")), boldCode}, a...)...)
-	fmt.Fprint(os.Stdout, ResetCode)
+	fmt.Fprint(Out(), append([]interface{}{Bold(Cyan(opSymbol + " ")), boldCode}, a...)...)
+	fmt.Fprint(Out(), ResetCode)
 }
 
 func SprintOperationInfo(a ...interface{}) string {
@@ -28,11 +47,11 @@ func SprintOperationInfo(a ...interface{}) string {
 }
 
 func Info(a ...interface{}) {
-	fmt.Fprint(os.Stdout, append([]interface{}{Bold(green(arrow + " "))}, a...)...)
+	fmt.Fprint(Out(), append([]interface{}{Bold(green(arrow + " "))}, a...)...)
 }
 
 func Infoln(a ...interface{}) {
-	fmt.Fprintln(os.Stdout, append([]interface{}{Bold(green(arrow))}, a...)...)
+	fmt.Fprintln(Out(), append([]interface{}{Bold(green(arrow))}, a...)...)
 }
 
 func SprintWarn(a ...interface{}) string {
@@ -40,11 +59,11 @@ func SprintWarn(a ...interface{}) string {
 }
 
 func Warn(a ...interface{}) {
-	fmt.Fprint(os.Stdout, append([]interface{}{Bold(yellow(smallArrow + " "))}, a...)...)
+	fmt.Fprint(Out(), append([]interface{}{Bold(yellow(smallArrow + " "))}, a...)...)
 }
 
 func Warnln(a ...interface{}) {
-	fmt.Fprintln(os.Stdout, append([]interface{}{Bold(yellow(smallArrow))}, a...)...)
+	fmt.Fprintln(Out(), append([]interface{}{Bold(yellow(smallArrow))}, a...)...)
 }
 
 func SprintError(a ...interface{}) string {
@@ -64,5 +83,5 @@ func PrintInfoValue(str, value string) {
 		value = gotext.Get("None")
 	}
 
-	fmt.Fprintf(os.Stdout, Bold("%-16s%s")+" %s\n", str, ":", value)
+	fmt.Fprintf(Out(), Bold("%-16s%s")+" %s\n", str, ":", value)
 }
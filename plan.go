@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+)
+
+// transactionPlan is the machine readable form of what an install would do
+type transactionPlan struct {
+	Repo      []planRepoPkg       `json:"repo"`
+	Aur       []planBase          `json:"aur"`
+	Make      []string            `json:"make"`
+	Conflicts map[string][]string `json:"conflicts"`
+	Ignore    []string            `json:"ignore"`
+	Commands  []planCommand       `json:"commands"`
+}
+
+type planRepoPkg struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	DB      string `json:"db"`
+}
+
+type planBase struct {
+	Pkgbase  string   `json:"pkgbase"`
+	Version  string   `json:"version"`
+	Packages []string `json:"packages"`
+	// Depends lists the pkgbases that are built and installed first
+	Depends []string `json:"depends"`
+}
+
+// planCommand is a command that would be run. The package files passed to
+// pacman -U and repo-add, and the ones makechrootpkg installs into the
+// chroot with -I, are only known after building, so Packages lists the names
+// of the packages instead.
+type planCommand struct {
+	Args     []string `json:"args"`
+	Dir      string   `json:"dir,omitempty"`
+	Packages []string `json:"packages,omitempty"`
+}
+
+func sortedSet(set stringset.StringSet) []string {
+	list := set.ToSlice()
+	sort.Strings(list)
+	return list
+}
+
+// makeTransactionPlan lists what an install of do would run, building the
+// bases in incompatible with --ignorearch
+func makeTransactionPlan(cmdArgs, arguments *settings.Arguments, do *dep.Order,
+	conflicts stringset.MapStringSet, ignore, incompatible stringset.StringSet) *transactionPlan {
+	plan := &transactionPlan{
+		Repo:      make([]planRepoPkg, 0, len(do.Repo)),
+		Aur:       make([]planBase, 0, len(do.Aur)),
+		Make:      do.GetMake(),
+		Conflicts: make(map[string][]string, len(conflicts)),
+		Ignore:    sortedSet(ignore),
+		Commands:  make([]planCommand, 0),
+	}
+
+	for _, pkg := range do.Repo {
+		plan.Repo = append(plan.Repo, planRepoPkg{pkg.Name(), pkg.Version(), pkg.DB().Name()})
+	}
+
+	for _, base := range do.Aur {
+		packages := make([]string, 0, len(base))
+		for _, split := range base {
+			packages = append(packages, split.Name)
+		}
+
+		plan.Aur = append(plan.Aur, planBase{
+			Pkgbase:  base.Pkgbase(),
+			Version:  base.Version(),
+			Packages: packages,
+			Depends:  sortedSet(do.BaseDeps[base.Pkgbase()]),
+		})
+	}
+
+	for name, pkgs := range conflicts {
+		plan.Conflicts[name] = sortedSet(pkgs)
+	}
+
+	if cmdArgs.ExistsArg("y", "refresh") {
+		refresh := cmdArgs.Copy()
+		refresh.Op = "S"
+		refresh.DelArg("u", "sysupgrade")
+		refresh.ClearTargets()
+		plan.Commands = append(plan.Commands, planCommand{Args: pacmanArgs(refresh)})
+	}
+
+	repo := arguments.Copy()
+	repo.DelArg("y", "refresh")
+	if len(repo.Targets) > 0 || repo.ExistsArg("u", "sysupgrade") {
+		plan.Commands = append(plan.Commands, planCommand{Args: pacmanArgs(repo)})
+	}
+
+	command := func(cmd *exec.Cmd, packages []string) planCommand {
+		return planCommand{Args: cmd.Args, Dir: cmd.Dir, Packages: packages}
+	}
+
+	for _, base := range do.Aur {
+		dir := filepath.Join(config.BuildDir, base.Pkgbase())
+		plan.Commands = append(plan.Commands, command(sourcesCmd(dir, incompatible.Get(base.Pkgbase())), nil))
+	}
+
+	// in the chroot the packages of the AUR dependencies built before are
+	// installed first
+	scheduler := newBuildScheduler(do)
+	bases := make(map[string]dep.Base, len(do.Aur))
+	for _, base := range do.Aur {
+		bases[base.Pkgbase()] = base
+	}
+	injected := func(base dep.Base) []string {
+		if !config.Chroot {
+			return nil
+		}
+
+		pkgs := make([]string, 0)
+		for _, depBase := range sortedSet(scheduler.allDeps(base.Pkgbase())) {
+			for _, split := range bases[depBase] {
+				pkgs = append(pkgs, split.Name)
+			}
+		}
+
+		return pkgs
+	}
+
+	// built packages are installed with --noconfirm unless they conflict
+	installCmds := func(pkgs []string) []planCommand {
+		oldConfirm := config.NoConfirm
+		config.NoConfirm = true
+		if !config.UseAsk || !cmdArgs.ExistsArg("ask") {
+			for _, pkg := range pkgs {
+				if _, ok := conflicts[pkg]; ok {
+					config.NoConfirm = false
+				}
+			}
+		}
+
+		cmds := make([]planCommand, 0, 3)
+		arguments := installArguments(cmdArgs)
+		if config.LocalRepo {
+			cmds = append(cmds,
+				planCommand{Args: []string{"repo-add", "-R", localRepoDB()}, Packages: pkgs},
+				planCommand{Args: refreshLocalRepoArgs()},
+				planCommand{Args: pacmanArgs(localRepoArguments(arguments, pkgs))})
+		} else {
+			cmds = append(cmds, planCommand{Args: pacmanArgs(arguments), Packages: pkgs})
+		}
+
+		config.NoConfirm = oldConfirm
+		return cmds
+	}
+
+	// with batchinstall all packages are installed in one go after building
+	pkgs := make([]string, 0)
+	for _, base := range do.Aur {
+		dir := filepath.Join(config.BuildDir, base.Pkgbase())
+		ignoreArch := incompatible.Get(base.Pkgbase())
+		plan.Commands = append(plan.Commands,
+			command(pkgverCmd(dir, ignoreArch), nil),
+			command(buildCmd(dir, nil, ignoreArch), injected(base)))
+
+		for _, split := range base {
+			pkgs = append(pkgs, split.Name)
+		}
+
+		if !config.BatchInstall {
+			plan.Commands = append(plan.Commands, installCmds(pkgs)...)
+			pkgs = make([]string, 0)
+		}
+	}
+
+	if len(pkgs) > 0 {
+		plan.Commands = append(plan.Commands, installCmds(pkgs)...)
+	}
+
+	return plan
+}
+
+func printTransactionPlan(w io.Writer, plan *transactionPlan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+)
+
+func TestMakeTransactionPlanCommands(t *testing.T) {
+	oldConfig := config
+	defer func() { config = oldConfig }()
+
+	lib := dep.Base{{Name: "libfoo", PackageBase: "libfoo", Version: "1.0-1"}}
+	app := dep.Base{{Name: "foo", PackageBase: "foo", Version: "2.0-1"}}
+
+	install := []string{"sudo", "pacman", "-U", "--noconfirm", "--config", "/etc/pacman.conf", "--"}
+	refresh := []string{"sudo", "pacman", "-Sy", "--config", "/build/localrepo-pacman.conf"}
+	repoInstall := func(pkg string) []string {
+		return []string{"sudo", "pacman", "-S", "--noconfirm", "--config", "/etc/pacman.conf", "--", "aur/" + pkg}
+	}
+
+	testCases := []struct {
+		name         string
+		chroot       bool
+		localRepo    bool
+		incompatible []string
+		want         []planCommand
+	}{
+		{
+			name: "host",
+			want: []planCommand{
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}, Dir: "/build/libfoo"},
+				{Args: install, Packages: []string{"libfoo"}},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}, Dir: "/build/foo"},
+				{Args: install, Packages: []string{"foo"}},
+			},
+		},
+		{
+			name:         "incompatible",
+			incompatible: []string{"foo"},
+			want: []planCommand{
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "--verifysource", "-Ccf", "--ignorearch"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}, Dir: "/build/libfoo"},
+				{Args: install, Packages: []string{"libfoo"}},
+				{Args: []string{"makepkg", "--nobuild", "-fC", "--ignorearch"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver", "--ignorearch"}, Dir: "/build/foo"},
+				{Args: install, Packages: []string{"foo"}},
+			},
+		},
+		{
+			name:      "localrepo",
+			localRepo: true,
+			want: []planCommand{
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}, Dir: "/build/libfoo"},
+				{Args: []string{"repo-add", "-R", "/srv/repo/aur.db.tar.gz"}, Packages: []string{"libfoo"}},
+				{Args: refresh},
+				{Args: repoInstall("libfoo")},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "-cf", "--noconfirm", "--noextract", "--noprepare", "--holdver"}, Dir: "/build/foo"},
+				{Args: []string{"repo-add", "-R", "/srv/repo/aur.db.tar.gz"}, Packages: []string{"foo"}},
+				{Args: refresh},
+				{Args: repoInstall("foo")},
+			},
+		},
+		{
+			name:   "chroot",
+			chroot: true,
+			want: []planCommand{
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/libfoo"},
+				{Args: []string{"makepkg", "--verifysource", "-Ccf"}, Dir: "/build/foo"},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/libfoo"},
+				{Args: []string{"makechrootpkg", "-c", "-r", "/chroot", "-l", "libfoo", "--", "-f", "--noconfirm", "--holdver"},
+					Dir: "/build/libfoo", Packages: []string{}},
+				{Args: install, Packages: []string{"libfoo"}},
+				{Args: []string{"makepkg", "--nobuild", "-fC"}, Dir: "/build/foo"},
+				{Args: []string{"makechrootpkg", "-c", "-r", "/chroot", "-l", "foo", "--", "-f", "--noconfirm", "--holdver"},
+					Dir: "/build/foo", Packages: []string{"libfoo"}},
+				{Args: install, Packages: []string{"foo"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config = settings.MakeConfig()
+			config.Runtime = &settings.Runtime{Mode: settings.ModeAny}
+			config.BuildDir = "/build"
+			config.ChrootDir = "/chroot"
+			config.PacmanConf = "/etc/pacman.conf"
+			config.Chroot = tc.chroot
+			config.LocalRepo = tc.localRepo
+			config.LocalRepoName = "aur"
+			config.LocalRepoDir = "/srv/repo"
+
+			cmdArgs := settings.MakeArguments()
+			cmdArgs.Op = "S"
+			cmdArgs.AddTarget("foo")
+			arguments := cmdArgs.Copy()
+			arguments.ClearTargets()
+
+			do := &dep.Order{
+				Aur:      []dep.Base{lib, app},
+				Runtime:  stringset.FromSlice([]string{"libfoo", "foo"}),
+				BaseDeps: stringset.MapStringSet{"foo": stringset.FromSlice([]string{"libfoo"})},
+			}
+
+			plan := makeTransactionPlan(cmdArgs, arguments, do, make(stringset.MapStringSet),
+				make(stringset.StringSet), stringset.FromSlice(tc.incompatible))
+			assert.Equal(t, tc.want, plan.Commands)
+		})
+	}
+}
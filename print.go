@@ -226,11 +226,11 @@ func (u upSlice) print() {
 	for k, i := range u {
 		left, right := getVersionDiff(i.LocalVersion, i.RemoteVersion)
 
-		fmt.Print(magenta(fmt.Sprintf(numberPadding, len(u)-k)))
+		fmt.Fprint(text.Out(), magenta(fmt.Sprintf(numberPadding, len(u)-k)))
 
-		fmt.Printf(namePadding, i.StylizedNameWithRepository())
+		fmt.Fprintf(text.Out(), namePadding, i.StylizedNameWithRepository())
 
-		fmt.Printf("%s -> %s\n", fmt.Sprintf(versionPadding, left), right)
+		fmt.Fprintf(text.Out(), "%s -> %s\n", fmt.Sprintf(versionPadding, left), right)
 	}
 }
 
@@ -485,11 +485,14 @@ func printUpdateList(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, enabl
 	}
 
 	noTargets := len(targets) == 0
+	records := make([]jsonUpgrade, 0)
 
 	if !cmdArgs.ExistsArg("m", "foreign") {
 		for _, pkg := range repoUp {
 			if noTargets || targets.Get(pkg.Name) {
-				if cmdArgs.ExistsArg("q", "quiet") {
+				if config.Runtime.JSON {
+					records = append(records, upgradeRecord(&pkg))
+				} else if cmdArgs.ExistsArg("q", "quiet") {
 					fmt.Printf("%s\n", pkg.Name)
 				} else {
 					fmt.Printf("%s %s -> %s\n", bold(pkg.Name), green(pkg.LocalVersion), green(pkg.RemoteVersion))
@@ -502,7 +505,9 @@ func printUpdateList(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle, enabl
 	if !cmdArgs.ExistsArg("n", "native") {
 		for _, pkg := range aurUp {
 			if noTargets || targets.Get(pkg.Name) {
-				if cmdArgs.ExistsArg("q", "quiet") {
+				if config.Runtime.JSON {
+					records = append(records, upgradeRecord(&pkg))
+				} else if cmdArgs.ExistsArg("q", "quiet") {
 					fmt.Printf("%s\n", pkg.Name)
 				} else {
 					fmt.Printf("%s %s -> %s\n", bold(pkg.Name), green(pkg.LocalVersion), green(pkg.RemoteVersion))
@@ -532,6 +537,15 @@ outer:
 		missing = true
 	}
 
+	if config.Runtime.JSON {
+		if err := printJSON(os.Stdout, records); err != nil {
+			return err
+		}
+
+		// like pacman -Qu, an empty list fails
+		missing = missing || len(records) == 0
+	}
+
 	if missing {
 		return fmt.Errorf("")
 	}
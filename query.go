@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -66,6 +67,8 @@ func (q aurQuery) Swap(i, j int) {
 	q[i], q[j] = q[j], q[i]
 }
 
+// getSearchBy returns the field the RPC searches by. Fields only the search
+// index knows fall back to names and descriptions.
 func getSearchBy(value string) rpc.By {
 	switch value {
 	case "name":
@@ -85,6 +88,21 @@ func getSearchBy(value string) rpc.By {
 	}
 }
 
+// loadSearchIndex refreshes the offline search index on the interval of the
+// completion cache and loads it. A stale index is used when it can't be
+// refreshed.
+func loadSearchIndex() (*query.SearchIndex, error) {
+	path := config.Runtime.AURIndexPath
+	if err := query.UpdateSearchIndex(config.AURURL, path, config.CompletionInterval, false); err != nil {
+		if _, statErr := os.Stat(path); statErr != nil {
+			return nil, err
+		}
+		text.Warnln(gotext.Get("unable to refresh the search index, using the old one: %s", err))
+	}
+
+	return query.LoadSearchIndex(path)
+}
+
 // NarrowSearch searches AUR and narrows based on subarguments
 func narrowSearch(pkgS []string, sortS bool) (aurQuery, error) {
 	var r []rpc.Pkg
@@ -97,8 +115,20 @@ func narrowSearch(pkgS []string, sortS bool) (aurQuery, error) {
 		return nil, nil
 	}
 
+	if config.SearchIndex {
+		index, indexErr := loadSearchIndex()
+		if indexErr == nil {
+			aq := aurQuery(index.Search(pkgS, config.SearchBy))
+			if sortS {
+				sort.Sort(aq)
+			}
+			return aq, nil
+		}
+		text.Warnln(gotext.Get("unable to use the search index, searching the AUR: %s", indexErr))
+	}
+
 	for i, word := range pkgS {
-		r, err = rpc.SearchBy(word, by)
+		r, err = query.Client.Search(word, by)
 		if err == nil {
 			usedIndex = i
 			break
@@ -146,7 +176,7 @@ func narrowSearch(pkgS []string, sortS bool) (aurQuery, error) {
 }
 
 // SyncSearch presents a query to the local repos and to the AUR.
-func syncSearch(pkgS []string, alpmHandle *alpm.Handle) (err error) {
+func syncSearch(w io.Writer, pkgS []string, alpmHandle *alpm.Handle) (err error) {
 	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
 	var aurErr error
 	var repoErr error
@@ -163,6 +193,10 @@ func syncSearch(pkgS []string, alpmHandle *alpm.Handle) (err error) {
 		}
 	}
 
+	if config.Runtime.JSON {
+		return printSearchJSON(w, aq, pq, aurErr, alpmHandle)
+	}
+
 	switch config.SortMode {
 	case settings.TopDown:
 		if config.Runtime.Mode == settings.ModeRepo || config.Runtime.Mode == settings.ModeAny {
@@ -190,8 +224,48 @@ func syncSearch(pkgS []string, alpmHandle *alpm.Handle) (err error) {
 	return nil
 }
 
+// printSearchJSON prints the results of a search in the order they are
+// listed in. Like pacman's, the search fails when nothing matched.
+func printSearchJSON(w io.Writer, aq aurQuery, pq repoQuery, aurErr error, alpmHandle *alpm.Handle) error {
+	localDB, _ := alpmHandle.LocalDB()
+	results := make([]jsonSearchResult, 0, len(aq)+len(pq))
+
+	addRepo := func() {
+		for i := range pq {
+			results = append(results, repoSearchResult(&pq[i], installedVersion(localDB, pq[i].Name())))
+		}
+	}
+	addAUR := func() {
+		for i := range aq {
+			results = append(results, aurSearchResult(&aq[i], installedVersion(localDB, aq[i].Name)))
+		}
+	}
+
+	if config.SortMode == settings.BottomUp {
+		addAUR()
+		addRepo()
+	} else {
+		addRepo()
+		addAUR()
+	}
+
+	if err := printJSON(w, results); err != nil {
+		return err
+	}
+
+	if aurErr != nil {
+		return errors.New(gotext.Get("error during AUR search: %s", aurErr))
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("")
+	}
+
+	return nil
+}
+
 // SyncInfo serves as a pacman -Si for repo packages and AUR packages.
-func syncInfo(cmdArgs *settings.Arguments, pkgS []string, alpmHandle *alpm.Handle) error {
+func syncInfo(w io.Writer, cmdArgs *settings.Arguments, pkgS []string, alpmHandle *alpm.Handle) error {
 	var info []*rpc.Pkg
 	missing := false
 	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
@@ -215,6 +289,10 @@ func syncInfo(cmdArgs *settings.Arguments, pkgS []string, alpmHandle *alpm.Handl
 		}
 	}
 
+	if config.Runtime.JSON {
+		return printInfoJSON(w, repoS, aurS, info, missing, alpmHandle)
+	}
+
 	// Repo always goes first
 	if len(repoS) != 0 {
 		arguments := cmdArgs.Copy()
@@ -244,6 +322,64 @@ func syncInfo(cmdArgs *settings.Arguments, pkgS []string, alpmHandle *alpm.Handl
 	return err
 }
 
+// printInfoJSON prints the info of the repo targets read from the sync dbs,
+// followed by the info of the AUR targets, in the order they were given
+func printInfoJSON(w io.Writer, repoS, aurS []string, info []*rpc.Pkg, missing bool, alpmHandle *alpm.Handle) error {
+	dbList, err := alpmHandle.SyncDBs()
+	if err != nil {
+		return err
+	}
+
+	localDB, _ := alpmHandle.LocalDB()
+	records := make([]jsonInfo, 0, len(repoS)+len(info))
+
+	for _, target := range repoS {
+		dbName, name := text.SplitDBFromName(target)
+
+		var pkg *alpm.Package
+		_ = dbList.ForEach(func(db alpm.DB) error {
+			if pkg == nil && (dbName == "" || db.Name() == dbName) {
+				pkg = db.Pkg(name)
+			}
+			return nil
+		})
+
+		if pkg == nil {
+			text.Errorln(gotext.Get("package '%s' was not found", target))
+			missing = true
+			continue
+		}
+
+		records = append(records, repoInfoRecord(pkg, installedVersion(localDB, name)))
+	}
+
+	byName := make(map[string]*rpc.Pkg, len(info))
+	for _, pkg := range info {
+		byName[pkg.Name] = pkg
+	}
+
+	for _, target := range aurS {
+		_, name := text.SplitDBFromName(target)
+		pkg, ok := byName[name]
+		if !ok {
+			missing = true
+			continue
+		}
+
+		records = append(records, aurInfoRecord(pkg, installedVersion(localDB, name)))
+	}
+
+	if err := printJSON(w, records); err != nil {
+		return err
+	}
+
+	if missing || len(records) == 0 {
+		return fmt.Errorf("")
+	}
+
+	return nil
+}
+
 // Search handles repo searches. Creates a RepoSearch struct.
 func queryRepo(pkgInputN []string, alpmHandle *alpm.Handle) (s repoQuery, err error) {
 	dbList, err := alpmHandle.SyncDBs()
@@ -450,7 +586,7 @@ func aurInfo(names []string, warnings *query.AURWarnings) ([]*rpc.Pkg, error) {
 
 	makeRequest := func(n, max int) {
 		defer wg.Done()
-		tempInfo, requestErr := rpc.Info(names[n:max])
+		tempInfo, requestErr := query.Client.Info(names[n:max])
 		errs.Add(requestErr)
 		if requestErr != nil {
 			return
@@ -522,32 +658,14 @@ func aurPkgbuilds(names []string) ([]string, error) {
 		defer wg.Done()
 
 		for _, name := range names[n:max] {
-			values := url.Values{}
-			values.Set("h", name)
-
-			url := "https://aur.archlinux.org/cgit/aur.git/plain/PKGBUILD?"
-
-			resp, err := http.Get(url + values.Encode())
+			pkgbuild, err := query.Client.PKGBUILD(name)
 			if err != nil {
 				errs.Add(err)
 				continue
 			}
-			if resp.StatusCode != 200 {
-				errs.Add(fmt.Errorf("error code %d for package %s", resp.StatusCode, name))
-				continue
-			}
-			defer resp.Body.Close()
-
-			body, readErr := ioutil.ReadAll(resp.Body)
-			pkgbuild := string(body)
-
-			if readErr != nil {
-				errs.Add(readErr)
-				continue
-			}
 
 			mux.Lock()
-			pkgbuilds = append(pkgbuilds, pkgbuild)
+			pkgbuilds = append(pkgbuilds, string(pkgbuild))
 			mux.Unlock()
 		}
 	}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"debug/elf"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	alpm "github.com/Jguer/go-alpm"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/settings"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// directories the dynamic linker searches besides the ones of ld.so.conf
+var defaultLibDirs = []string{"/usr/lib", "/usr/lib32", "/lib", "/lib64", "/usr/local/lib"}
+
+// libDirs returns the directories the dynamic linker searches below root
+func libDirs(root string) []string {
+	dirs := append([]string{}, defaultLibDirs...)
+	seen := make(stringset.StringSet)
+
+	dirs = append(dirs, ldConfDirs(root, "/etc/ld.so.conf", seen)...)
+
+	// ld.so.conf includes these on Arch, they are read in case it doesn't
+	confs, _ := filepath.Glob(filepath.Join(root, "etc/ld.so.conf.d/*.conf"))
+	for _, conf := range confs {
+		dirs = append(dirs, ldConfDirs(root, strings.TrimPrefix(conf, root), seen)...)
+	}
+
+	for i, dir := range dirs {
+		dirs[i] = filepath.Join(root, dir)
+	}
+
+	return dirs
+}
+
+// ldConfDirs returns the directories listed in an ld.so.conf file below
+// root, following its include lines. Files in seen are skipped.
+func ldConfDirs(root, conf string, seen stringset.StringSet) []string {
+	dirs := make([]string, 0)
+	conf = filepath.Join("/", conf)
+	if seen.Get(conf) {
+		return dirs
+	}
+	seen.Set(conf)
+
+	file, err := os.Open(filepath.Join(root, conf))
+	if err != nil {
+		return dirs
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] != "include" {
+			if strings.HasPrefix(fields[0], "/") {
+				dirs = append(dirs, fields[0])
+			}
+			continue
+		}
+
+		for _, pattern := range fields[1:] {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(conf), pattern)
+			}
+
+			includes, _ := filepath.Glob(filepath.Join(root, pattern))
+			for _, include := range includes {
+				dirs = append(dirs, ldConfDirs(root, strings.TrimPrefix(include, root), seen)...)
+			}
+		}
+	}
+
+	return dirs
+}
+
+// elfKind is what a library has to match to be loaded for an ELF file, so
+// 32 bit libraries don't stand in for 64 bit ones
+type elfKind struct {
+	class   elf.Class
+	machine elf.Machine
+}
+
+// libKinds caches the elfKind of the files looked at, the zero value for the
+// ones that aren't ELF files
+type libKinds map[string]elfKind
+
+func (kinds libKinds) get(path string) elfKind {
+	if kind, ok := kinds[path]; ok {
+		return kind
+	}
+
+	var kind elfKind
+	if file, err := elf.Open(path); err == nil {
+		kind = elfKind{file.Class, file.Machine}
+		file.Close()
+	}
+	kinds[path] = kind
+
+	return kind
+}
+
+// runPaths returns the RPATH and RUNPATH entries of an ELF file with $ORIGIN
+// expanded to origin
+func runPaths(file *elf.File, origin string) []string {
+	paths := make([]string, 0)
+
+	for _, tag := range []elf.DynTag{elf.DT_RUNPATH, elf.DT_RPATH} {
+		values, err := file.DynString(tag)
+		if err != nil {
+			continue
+		}
+
+		for _, value := range values {
+			for _, path := range strings.Split(value, ":") {
+				path = strings.Replace(path, "${ORIGIN}", origin, -1)
+				path = strings.Replace(path, "$ORIGIN", origin, -1)
+				if path != "" {
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+
+	return paths
+}
+
+// missingLibraries returns the shared libraries an ELF file needs that exist
+// in none of dirs or of its own run paths for its class and machine. Files
+// that aren't ELF files need nothing.
+func missingLibraries(path string, dirs []string, kinds libKinds) []string {
+	file, err := elf.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	libs, err := file.ImportedLibraries()
+	if err != nil {
+		return nil
+	}
+
+	dirs = append(runPaths(file, filepath.Dir(path)), dirs...)
+	kind := elfKind{file.Class, file.Machine}
+	missing := make([]string, 0)
+
+	for _, lib := range libs {
+		found := false
+		for _, dir := range dirs {
+			if kinds.get(filepath.Join(dir, lib)) == kind {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, lib)
+		}
+	}
+
+	return missing
+}
+
+var sitePackagesPath = regexp.MustCompile(`^usr/lib/python(\d+\.\d+)/site-packages/`)
+
+// stalePythonVersions returns the python versions files were installed for
+// that current replaced: older ones of the same major version whose
+// interpreter isn't installed below root anymore
+func stalePythonVersions(files []string, current, root string) []string {
+	stale := make(stringset.StringSet)
+	major := strings.SplitN(current, ".", 2)[0]
+
+	for _, file := range files {
+		match := sitePackagesPath.FindStringSubmatch(file)
+		if match == nil || match[1] == current || strings.SplitN(match[1], ".", 2)[0] != major {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(root, "usr/bin/python"+match[1])); err == nil {
+			continue
+		}
+
+		stale.Set(match[1])
+	}
+
+	versions := stale.ToSlice()
+	sort.Strings(versions)
+
+	return versions
+}
+
+// pythonVersion returns the major.minor version of the installed python, ""
+// when it isn't installed
+func pythonVersion(localDB *alpm.DB) string {
+	pkg := localDB.Pkg("python")
+	if pkg == nil {
+		return ""
+	}
+
+	version := pkg.Version()
+	if i := strings.Index(version, ":"); i >= 0 {
+		version = version[i+1:]
+	}
+
+	split := strings.SplitN(version, ".", 3)
+	if len(split) < 2 {
+		return ""
+	}
+
+	return split[0] + "." + split[1]
+}
+
+// rebuildReasons returns why an installed package has to be rebuilt, nothing
+// when it doesn't
+func rebuildReasons(pkg *alpm.Package, root string, dirs []string, kinds libKinds, python string) []string {
+	reasons := make([]string, 0)
+	missing := make(stringset.StringSet)
+	names := make([]string, 0)
+
+	for _, file := range pkg.Files() {
+		names = append(names, file.Name)
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+
+		path := filepath.Join(root, file.Name)
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		// only executables and shared objects link to libraries
+		if info.Mode()&0o111 == 0 && !strings.Contains(filepath.Base(path), ".so") {
+			continue
+		}
+
+		for _, lib := range missingLibraries(path, dirs, kinds) {
+			if !missing.Get(lib) {
+				missing.Set(lib)
+				reasons = append(reasons, gotext.Get("%s is missing (needed by /%s)", lib, file.Name))
+			}
+		}
+	}
+
+	if python != "" {
+		for _, version := range stalePythonVersions(names, python, root) {
+			reasons = append(reasons, gotext.Get("installed for python %s instead of %s", version, python))
+		}
+	}
+
+	return reasons
+}
+
+// rebuildCheck looks for installed foreign packages that link to shared
+// libraries which don't exist anymore or were installed for an older python,
+// and offers to rebuild them
+func rebuildCheck(cmdArgs *settings.Arguments, alpmHandle *alpm.Handle) error {
+	root, err := alpmHandle.Root()
+	if err != nil {
+		return err
+	}
+
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	remote, _, err := query.GetRemotePackages(alpmHandle)
+	if err != nil {
+		return err
+	}
+
+	dirs := libDirs(root)
+	kinds := make(libKinds)
+	python := pythonVersion(localDB)
+	targets := make([]string, 0)
+
+	for i := range remote {
+		pkg := &remote[i]
+		reasons := rebuildReasons(pkg, root, dirs, kinds, python)
+		if len(reasons) == 0 {
+			continue
+		}
+
+		if len(targets) == 0 {
+			text.OperationInfoln(gotext.Get("Packages that need a rebuild:"))
+		}
+		targets = append(targets, pkg.Name())
+
+		fmt.Println(bold(pkg.Name()) + " " + cyan(pkg.Version()))
+		for _, reason := range reasons {
+			fmt.Println("    " + reason)
+		}
+	}
+
+	if len(targets) == 0 {
+		text.Infoln(gotext.Get("No package needs a rebuild"))
+		return nil
+	}
+
+	if !text.ContinueTask(gotext.Get("Rebuild %d packages?", len(targets)), false, config.NoConfirm) {
+		return nil
+	}
+
+	arguments := cmdArgs.CopyGlobal()
+	arguments.Op = "S"
+	for _, target := range targets {
+		arguments.AddTarget("aur/" + target)
+	}
+	config.ReBuild = "yes"
+
+	return install(arguments, alpmHandle, false)
+}
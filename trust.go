@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// trustStore holds the AUR maintainers whose PKGBUILD changes are accepted
+// without a review, either for every pkgbase they maintain or for single
+// pkgbases
+type trustStore struct {
+	Maintainers []string          `json:"maintainers"`
+	Pkgbases    map[string]string `json:"pkgbases"`
+}
+
+func loadTrust(path string) (*trustStore, error) {
+	trust := &trustStore{Maintainers: []string{}, Pkgbases: make(map[string]string)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return trust, nil
+	} else if err != nil {
+		return nil, errors.New(gotext.Get("failed to open trust file '%s': %s", path, err))
+	}
+
+	if err = json.Unmarshal(data, trust); err != nil {
+		return nil, errors.New(gotext.Get("failed to read trust file '%s': %s", path, err))
+	}
+
+	if trust.Pkgbases == nil {
+		trust.Pkgbases = make(map[string]string)
+	}
+
+	return trust, nil
+}
+
+func (t *trustStore) save(path string) error {
+	sort.Strings(t.Maintainers)
+
+	data, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0o644)
+}
+
+// trusts returns whether changes made by maintainer to pkgbase are trusted.
+// A pkgbase is only trusted as long as it keeps the maintainer it was trusted
+// with.
+func (t *trustStore) trusts(pkgbase, maintainer string) bool {
+	if maintainer == "" {
+		return false
+	}
+
+	if trusted, ok := t.Pkgbases[pkgbase]; ok {
+		return trusted == maintainer
+	}
+
+	return stringset.FromSlice(t.Maintainers).Get(maintainer)
+}
+
+// removeBases returns the bases that aren't in toRemove
+func removeBases(bases, toRemove []dep.Base) []dep.Base {
+	kept := make([]dep.Base, 0, len(bases))
+	for _, base := range bases {
+		if !containsBase(toRemove, base) {
+			kept = append(kept, base)
+		}
+	}
+
+	return kept
+}
+
+// reviewMaintainers sorts out the bases whose diffs don't need a review
+// because their maintainer is trusted, and the ones that need one because
+// their maintainer changed since the last reviewed diff
+func reviewMaintainers(bases []dep.Base) (trusted, changed []dep.Base) {
+	trusted = make([]dep.Base, 0)
+	changed = make([]dep.Base, 0)
+
+	for _, base := range bases {
+		pkgbase := base.Pkgbase()
+		maintainer := base[0].Maintainer
+
+		if seen, ok := getSeenMaintainer(config.BuildDir, pkgbase); ok && seen != maintainer {
+			if maintainer == "" {
+				maintainer = gotext.Get("nobody")
+			}
+			text.Warnln(bold(red(gotext.Get("%s: the maintainer changed from %s to %s since the last review",
+				pkgbase, seen, maintainer))))
+			changed = append(changed, base)
+			continue
+		}
+
+		if savedTrust.trusts(pkgbase, maintainer) {
+			trusted = append(trusted, base)
+		}
+	}
+
+	return trusted, changed
+}
+
+func trustTargets(targets []string, maintainers bool) error {
+	if maintainers {
+		savedTrust.Maintainers = stringset.FromSlice(append(savedTrust.Maintainers, targets...)).ToSlice()
+		for _, maintainer := range targets {
+			text.OperationInfoln(gotext.Get("Trusting every package of %s", cyan(maintainer)))
+		}
+
+		return savedTrust.save(config.Runtime.TrustPath)
+	}
+
+	info, err := query.AURInfoPrint(targets, config.RequestSplitN)
+	if err != nil {
+		return err
+	}
+
+	found := make(stringset.StringSet)
+	for _, pkg := range info {
+		found.Set(pkg.Name)
+		if pkg.Maintainer == "" {
+			return errors.New(gotext.Get("%s has no maintainer", pkg.PackageBase))
+		}
+
+		savedTrust.Pkgbases[pkg.PackageBase] = pkg.Maintainer
+		text.OperationInfoln(gotext.Get("Trusting %s as long as it is maintained by %s", cyan(pkg.PackageBase), cyan(pkg.Maintainer)))
+	}
+
+	for _, target := range targets {
+		if !found.Get(target) {
+			return errors.New(gotext.Get("%s is not an AUR package", target))
+		}
+	}
+
+	return savedTrust.save(config.Runtime.TrustPath)
+}
+
+// untrustTargets removes the trust of the pkgbases and maintainers given
+func untrustTargets(targets []string) error {
+	for _, target := range targets {
+		removed := false
+
+		if _, ok := savedTrust.Pkgbases[target]; ok {
+			delete(savedTrust.Pkgbases, target)
+			removed = true
+		}
+
+		maintainers := stringset.FromSlice(savedTrust.Maintainers)
+		if maintainers.Get(target) {
+			maintainers.Remove(target)
+			savedTrust.Maintainers = maintainers.ToSlice()
+			removed = true
+		}
+
+		if !removed {
+			return errors.New(gotext.Get("%s is not trusted", target))
+		}
+		text.OperationInfoln(gotext.Get("No longer trusting %s", cyan(target)))
+	}
+
+	return savedTrust.save(config.Runtime.TrustPath)
+}
+
+func printTrust() {
+	sort.Strings(savedTrust.Maintainers)
+	for _, maintainer := range savedTrust.Maintainers {
+		fmt.Printf("%s %s\n", bold(maintainer), gotext.Get("(every package)"))
+	}
+
+	pkgbases := make([]string, 0, len(savedTrust.Pkgbases))
+	for pkgbase := range savedTrust.Pkgbases {
+		pkgbases = append(pkgbases, pkgbase)
+	}
+	sort.Strings(pkgbases)
+
+	for _, pkgbase := range pkgbases {
+		fmt.Printf("%s %s\n", bold(pkgbase), gotext.Get("(maintained by %s)", savedTrust.Pkgbases[pkgbase]))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yay-trust")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "trust.json")
+	trust, err := loadTrust(path)
+	assert.NoError(t, err)
+
+	trust.Maintainers = append(trust.Maintainers, "jguer")
+	trust.Pkgbases["yay-bin"] = "morganamilo"
+	assert.NoError(t, trust.save(path))
+
+	trust, err = loadTrust(path)
+	assert.NoError(t, err)
+
+	assert.True(t, trust.trusts("yay", "jguer"))
+	assert.True(t, trust.trusts("yay-bin", "morganamilo"))
+	// a trusted pkgbase isn't trusted anymore once its maintainer changes
+	assert.False(t, trust.trusts("yay-bin", "jguer"))
+	assert.False(t, trust.trusts("yay-git", "someone"))
+	assert.False(t, trust.trusts("yay-git", ""))
+}
@@ -234,7 +234,7 @@ func upDevel(remote []alpm.Package, aurdata map[string]*rpc.Pkg) upSlice {
 
 	toUpgrade := make(upSlice, 0, len(toUpdate))
 	for _, pkg := range toUpdate {
-		if pkg.ShouldIgnore() {
+		if pkg.ShouldIgnore() || isPinned(aurdata[pkg.Name()].PackageBase) {
 			printIgnoringPackage(pkg, "latest-commit")
 		} else {
 			toUpgrade = append(toUpgrade, upgrade{pkg.Name(), "devel", pkg.Version(), "latest-commit"})
@@ -258,7 +258,7 @@ func upAUR(remote []alpm.Package, aurdata map[string]*rpc.Pkg) upSlice {
 
 		if (config.TimeUpdate && (int64(aurPkg.LastModified) > pkg.BuildDate().Unix())) ||
 			(alpm.VerCmp(pkg.Version(), aurPkg.Version) < 0) {
-			if pkg.ShouldIgnore() {
+			if pkg.ShouldIgnore() || isPinned(aurPkg.PackageBase) {
 				printIgnoringPackage(pkg, aurPkg.Version)
 			} else {
 				toUpgrade = append(toUpgrade, upgrade{aurPkg.Name, "aur", pkg.Version(), aurPkg.Version})
@@ -374,7 +374,7 @@ func upgradePkgs(aurUp, repoUp upSlice) (ignore, aurNames stringset.StringSet, e
 	sort.Sort(repoUp)
 	sort.Sort(aurUp)
 	allUp := append(repoUp, aurUp...)
-	fmt.Printf("%s"+bold(" %d ")+"%s\n", bold(cyan("::")), allUpLen, bold(gotext.Get("Packages to upgrade.")))
+	fmt.Fprintf(text.Out(), "%s"+bold(" %d ")+"%s\n", bold(cyan("::")), allUpLen, bold(gotext.Get("Packages to upgrade.")))
 	allUp.print()
 
 	// TODO add noexclude
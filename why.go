@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	alpm "github.com/Jguer/go-alpm"
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/query"
+	"github.com/Jguer/yay/v10/pkg/stringset"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+// whyChains walks the requirements of name up to every root, walking on
+// past roots that are required themselves. It returns the shortest chain to
+// every root, each starting at the root and ending with name. The type of a
+// requirement is the one it has on the next package of the chain.
+func whyChains(name string, requiredBy func(string) []dep.Requirement, isRoot func(string) bool) [][]dep.Requirement {
+	type visit struct {
+		req    dep.Requirement
+		parent *visit
+	}
+
+	chains := make([][]dep.Requirement, 0)
+	seen := stringset.Make(name)
+	queue := []*visit{{req: dep.Requirement{Name: name}}}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, req := range requiredBy(current.req.Name) {
+			if seen.Get(req.Name) {
+				continue
+			}
+			seen.Set(req.Name)
+
+			next := &visit{req, current}
+			queue = append(queue, next)
+			if !isRoot(req.Name) {
+				continue
+			}
+
+			chain := make([]dep.Requirement, 0)
+			for v := next; v != nil; v = v.parent {
+				chain = append(chain, v.req)
+			}
+			chains = append(chains, chain)
+		}
+	}
+
+	return chains
+}
+
+// formatChain renders a chain as root -> ... -> name, naming every
+// requirement that isn't a plain dependency
+func formatChain(chain []dep.Requirement) string {
+	var b strings.Builder
+
+	for i, req := range chain {
+		if i > 0 {
+			if edgeType := chain[i-1].Type; edgeType != dep.EdgeDepends {
+				fmt.Fprintf(&b, " -[%s]-> ", edgeType)
+			} else {
+				b.WriteString(" -> ")
+			}
+		}
+
+		if i == 0 {
+			b.WriteString(bold(req.Name))
+		} else if i == len(chain)-1 {
+			b.WriteString(cyan(req.Name))
+		} else {
+			b.WriteString(req.Name)
+		}
+	}
+
+	return b.String()
+}
+
+// localRequiredBy returns the reverse dependencies of installed packages.
+// The local database doesn't know the make dependencies of AUR packages, so
+// they are looked up once the walk first asks for them.
+func localRequiredBy(alpmHandle *alpm.Handle) (func(string) []dep.Requirement, error) {
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return nil, err
+	}
+
+	_, remoteNames, err := query.GetPackageNamesBySource(alpmHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	var makeRequiredBy map[string][]dep.Requirement
+	return func(name string) []dep.Requirement {
+		if makeRequiredBy == nil {
+			makeRequiredBy = make(map[string][]dep.Requirement)
+			for pkgName, deps := range foreignMakeDepends(localDB, remoteNames) {
+				for i, edgeType := range [2]string{dep.EdgeMakeDepends, dep.EdgeCheckDepends} {
+					for _, depString := range deps[i] {
+						if satisfier, err := localDB.PkgCache().FindSatisfier(depString); err == nil {
+							makeRequiredBy[satisfier.Name()] = append(makeRequiredBy[satisfier.Name()],
+								dep.Requirement{Name: pkgName, Type: edgeType})
+						}
+					}
+				}
+			}
+		}
+
+		requiredBy := make([]dep.Requirement, 0)
+		if pkg := localDB.Pkg(name); pkg != nil {
+			for _, requirer := range pkg.ComputeRequiredBy() {
+				requiredBy = append(requiredBy, dep.Requirement{Name: requirer, Type: dep.EdgeDepends})
+			}
+		}
+
+		return append(requiredBy, makeRequiredBy[name]...)
+	}, nil
+}
+
+// foreignMakeDepends returns the make and check dependencies of foreign
+// packages. They are read from the .SRCINFO in the build directory where
+// there is one, only the other packages are looked up on the AUR.
+func foreignMakeDepends(localDB *alpm.DB, names []string) map[string][2][]string {
+	deps := make(map[string][2][]string)
+	missing := make([]string, 0)
+
+	for _, name := range names {
+		pkg := localDB.Pkg(name)
+		if pkg == nil {
+			continue
+		}
+
+		srcinfo, err := gosrc.ParseFile(filepath.Join(config.BuildDir, pkg.Base(), ".SRCINFO"))
+		if err != nil {
+			missing = append(missing, name)
+			continue
+		}
+
+		deps[name] = [2][]string{archValues(srcinfo.MakeDepends), archValues(srcinfo.CheckDepends)}
+	}
+
+	if len(missing) == 0 {
+		return deps
+	}
+
+	info, err := query.AURInfoPrint(missing, config.RequestSplitN)
+	if err != nil {
+		text.Warnln(gotext.Get("unable to look up make dependencies on the AUR: %s", err))
+	}
+
+	for _, pkg := range info {
+		deps[pkg.Name] = [2][]string{pkg.MakeDepends, pkg.CheckDepends}
+	}
+
+	return deps
+}
+
+func archValues(list []gosrc.ArchString) []string {
+	values := make([]string, 0, len(list))
+	for _, value := range list {
+		values = append(values, value.Value)
+	}
+
+	return values
+}
+
+// printWhy explains why installed packages are installed: the chains of
+// requirements from every explicitly installed package that needs them, and
+// the packages they are optional for
+func printWhy(targets []string, alpmHandle *alpm.Handle) error {
+	localDB, err := alpmHandle.LocalDB()
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		if localDB.Pkg(target) == nil {
+			return fmt.Errorf(gotext.Get("%s is not installed", target))
+		}
+	}
+
+	requiredBy, err := localRequiredBy(alpmHandle)
+	if err != nil {
+		return err
+	}
+
+	isExplicit := func(name string) bool {
+		pkg := localDB.Pkg(name)
+		return pkg != nil && pkg.Reason() == alpm.PkgReasonExplicit
+	}
+
+	for _, target := range targets {
+		pkg := localDB.Pkg(target)
+		text.OperationInfoln(gotext.Get("Why %s is installed:", cyan(target)))
+
+		if isExplicit(target) {
+			fmt.Println("    " + gotext.Get("it was explicitly installed"))
+		}
+
+		chains := whyChains(target, requiredBy, isExplicit)
+		for _, chain := range chains {
+			fmt.Println("    " + formatChain(chain))
+		}
+
+		if optionalFor := pkg.ComputeOptionalFor(); len(optionalFor) > 0 {
+			fmt.Println("    " + gotext.Get("optional for: %s", strings.Join(optionalFor, " ")))
+		}
+
+		if len(chains) == 0 && !isExplicit(target) {
+			fmt.Println("    " + gotext.Get("no explicitly installed package needs it"))
+		}
+	}
+
+	return nil
+}
+
+// printWhyMake explains why every make dependency of an install is needed
+func printWhyMake(do *dep.Order) {
+	makeDeps := do.GetMake()
+	if len(makeDeps) == 0 {
+		return
+	}
+
+	text.Infoln(gotext.Get("Make dependencies are needed by:"))
+	for _, name := range makeDeps {
+		for _, chain := range whyChains(name, do.RequiredBy, do.Runtime.Get) {
+			fmt.Println("    " + formatChain(chain))
+		}
+	}
+}
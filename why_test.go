@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v10/pkg/dep"
+	"github.com/Jguer/yay/v10/pkg/text"
+)
+
+func TestWhyChains(t *testing.T) {
+	requiredBy := map[string][]dep.Requirement{
+		"python":  {{Name: "meson", Type: dep.EdgeDepends}, {Name: "pacman", Type: dep.EdgeDepends}},
+		"meson":   {{Name: "yay-git", Type: dep.EdgeMakeDepends}},
+		"pacman":  {{Name: "yay-git", Type: dep.EdgeDepends}, {Name: "base", Type: dep.EdgeDepends}},
+		"yay-git": {{Name: "world", Type: dep.EdgeDepends}},
+	}
+	explicit := map[string]bool{"yay-git": true, "base": true, "world": true}
+
+	chains := whyChains("python",
+		func(name string) []dep.Requirement { return requiredBy[name] },
+		func(name string) bool { return explicit[name] })
+
+	assert.Equal(t, [][]dep.Requirement{
+		{{Name: "yay-git", Type: dep.EdgeMakeDepends}, {Name: "meson", Type: dep.EdgeDepends}, {Name: "python"}},
+		{{Name: "base", Type: dep.EdgeDepends}, {Name: "pacman", Type: dep.EdgeDepends}, {Name: "python"}},
+		{
+			{Name: "world", Type: dep.EdgeDepends}, {Name: "yay-git", Type: dep.EdgeMakeDepends},
+			{Name: "meson", Type: dep.EdgeDepends}, {Name: "python"},
+		},
+	}, chains)
+
+	useColor := text.UseColor
+	defer func() { text.UseColor = useColor }()
+	text.UseColor = false
+	assert.Equal(t, "yay-git -[makedepends]-> meson -> python", formatChain(chains[0]))
+}